@@ -3,33 +3,35 @@ package bergamot
 import (
 	"fmt"
 	"os"
+	"sync"
+	"time"
 
+	"github.com/alauda/bergamot/diagnose"
+	"github.com/alauda/bergamot/log"
+
+	"github.com/spf13/cast"
 	"github.com/spf13/viper"
 )
 
-func init() {
-	// fmt.Println("init")
-	// configName := "app"
-	// if os.Getenv("CONFIG") != "" {
-	// 	configName = os.Getenv("CONFIG")
-	// }
-	// fmt.Println(configName)
-	// viper.SetConfigFile(configName)
-	// viper.AddConfigPath("$HOME")
-	// viper.AddConfigPath("$WORKDIR")
-	// viper.AddConfigPath(".")
-	// if os.Getenv("WORKDIR") != "" {
-	// 	fmt.Println("has workdir", os.Getenv("WORKDIR"))
-	// 	viper.AddConfigPath(os.Getenv("WORKDIR"))
-	// }
-
+// Datasource a resource built from an entry of the datasources section
+// like a database, a cache or a metrics client
+type Datasource interface {
+	// Kind returns the type of the datasource
+	Kind() string
 }
 
-type Datasource interface{}
-type Server interface{}
+// Server a server built from an entry of the servers section
+type Server interface {
+	// Kind returns the type of the server
+	Kind() string
+	// Start will start serving and block while serving
+	Start() error
+}
 
+// Parser parses the configuration and builds datasources and servers
 type Parser interface {
-	GetDatasources(*viper.Viper) map[string]Datasource
+	GetDatasources(*viper.Viper) (map[string]Datasource, error)
+	GetServers(*viper.Viper) (map[string]Server, error)
 }
 
 // App app name
@@ -38,35 +40,68 @@ type App struct {
 	Servers     map[string]Server
 	Component   string
 	Config      *viper.Viper
+	// HealthChecker checks all datasources that implement diagnose.Component
+	HealthChecker *diagnose.HealthChecker
+	// ShutdownTimeout deadline to drain servers when shutting down
+	ShutdownTimeout time.Duration
+	log             log.Logger
+
+	// reload mode
+	reload     bool
+	reloadLock sync.Mutex
+	callbacks  []ChangeCallback
+	// settings last applied settings
+	settings map[string]interface{}
 }
 
+const defaultShutdownTimeout = 30 * time.Second
+
 // New bootstrap an app with a provided the configuration
-func New(config *viper.Viper, parser Parser) error {
+func New(config *viper.Viper, parser Parser) (*App, error) {
 	err := config.ReadInConfig()
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	keys := config.AllKeys()
-	fmt.Println(keys)
-	for _, k := range keys {
-		fmt.Println(k)
+	if err = Validate(config); err != nil {
+		return nil, err
 	}
-
-	// fmt.Println("all stttings")
-	for k, v := range config.AllSettings() {
-		fmt.Println(k, ":", v)
+	config.SetDefault("shutdown_timeout", defaultShutdownTimeout)
+	config.SetDefault("diagnose_path", defaultDiagnosePath)
+	app := &App{
+		Component:       config.GetString("component"),
+		Config:          config,
+		ShutdownTimeout: config.GetDuration("shutdown_timeout"),
+		log:             log.NewLogger("bergamot"),
+		settings:        getFlatSettings(config),
 	}
+	app.applyLogging()
+	app.log.Debugf("Configuration: %v", RedactSettings(config.AllSettings()))
+	if app.Datasources, err = parser.GetDatasources(config); err != nil {
+		return nil, app.closeOnError(err)
+	}
+	if app.Servers, err = parser.GetServers(config); err != nil {
+		return nil, app.closeOnError(err)
+	}
+	if err = app.setupMetrics(); err != nil {
+		return nil, app.closeOnError(err)
+	}
+	if err = app.setupDiagnose(config.GetString("diagnose_path")); err != nil {
+		return nil, app.closeOnError(err)
+	}
+	return app, nil
+}
 
-	data := parser.GetDatasources(config)
-	fmt.Println(data)
-	// fmt.Println(config.AllSettings())
-
+// closeOnError closes the datasources built before a setup error
+// and returns the setup error
+func (a *App) closeOnError(err error) error {
+	if closeErr := a.Close(); closeErr != nil {
+		a.log.Errorf("Closing datasources after setup error: %v", closeErr)
+	}
 	return err
 }
 
 // Auto start and bootstrap service
-func Auto() error {
+func Auto() (*App, error) {
 	v := viper.New()
 	// setting configuration file name
 	configName := "config"
@@ -85,19 +120,71 @@ func Auto() error {
 }
 
 // DefaultParser default parser
+// will dispatch each entry of the datasources and servers sections
+// to the registered factory of its type. If the type is not set the entry name is used instead.
+// Use RegisterDatasource and RegisterServer to add new types
 type DefaultParser struct{}
 
-func (DefaultParser) GetDatasources(config *viper.Viper) map[string]Datasource {
+// GetDatasources builds all datasources in the datasources section
+func (DefaultParser) GetDatasources(config *viper.Viper) (map[string]Datasource, error) {
 	ds := make(map[string]Datasource)
-	var settings interface{}
-	for k, v := range config.AllSettings() {
-		if k == "datasources" {
-			settings = v
-			break
+	err := parseSection(config, "datasources", GetDatasourceSchema, func(name, kind string, sub *viper.Viper) error {
+		factory, _ := GetDatasourceFactory(kind)
+		value, err := factory(name, sub)
+		if err == nil {
+			ds[name] = value
 		}
-	}
-	fmt.Println("got this")
-	fmt.Println(settings)
+		return err
+	})
+	return ds, err
+}
 
-	return ds
+// GetServers builds all servers in the servers section
+func (DefaultParser) GetServers(config *viper.Viper) (map[string]Server, error) {
+	servers := make(map[string]Server)
+	err := parseSection(config, "servers", GetServerSchema, func(name, kind string, sub *viper.Viper) error {
+		factory, _ := GetServerFactory(kind)
+		value, err := factory(name, sub)
+		if err == nil {
+			servers[name] = value
+		}
+		return err
+	})
+	return servers, err
+}
+
+// buildFunc builds one entry of a section using the factory of its kind
+type buildFunc func(name, kind string, config *viper.Viper) error
+
+// parseSection iterates all the entries of a section in name order
+// each entry is resolved and validated against the schema of its kind before being built.
+// Validation errors are collected and returned together
+// while any other error will stop the parsing
+func parseSection(config *viper.Viper, section string, getSchema func(kind string) ([]Field, bool), build buildFunc) error {
+	entries := config.GetStringMap(section)
+	var errs ValidationErrors
+	for _, name := range sortedKeys(entries) {
+		path := fmt.Sprintf("%s.%s", section, name)
+		values, err := cast.ToStringMapE(entries[name])
+		if err != nil || values == nil {
+			errs = append(errs, NewValidationError(path, "expected map"))
+			continue
+		}
+		values, kind, err := prepareEntry(path, name, values, getSchema)
+		if err != nil {
+			errs.Add(err)
+			continue
+		}
+		// resolved values are only given to the factory
+		// to keep secrets out of the app configuration
+		sub := viper.New()
+		for k, v := range values {
+			sub.Set(k, v)
+		}
+		sub.SetDefault("component", config.GetString("component"))
+		if err = build(name, kind, sub); err != nil && !errs.Add(err) {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return errs.Err()
 }
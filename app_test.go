@@ -0,0 +1,178 @@
+package bergamot_test
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alauda/bergamot"
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/spf13/viper"
+)
+
+func TestDefaultParserValidation(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Config   string
+		Expected []string
+	}
+
+	table := []TestCase{
+		{
+			"unknown type",
+			"datasources:\n  kafka:\n    host: 0.0.0.0\n",
+			[]string{"datasources.kafka.type"},
+		},
+		{
+			"missing fields",
+			"datasources:\n  main:\n    type: mysql\n  cache:\n    type: redis\n    host: 0.0.0.0\n",
+			[]string{"datasources.cache.port", "datasources.main.host", "datasources.main.database"},
+		},
+		{
+			"not a map",
+			"datasources:\n  mysql: 1\n",
+			[]string{"datasources.mysql"},
+		},
+	}
+
+	for i, test := range table {
+		config := viper.New()
+		config.SetConfigType("yaml")
+		if err := config.ReadConfig(bytes.NewBufferString(test.Config)); err != nil {
+			t.Fatalf("%d - %s -- unexpected error reading config: %v", i, test.Name, err)
+		}
+		_, err := bergamot.DefaultParser{}.GetDatasources(config)
+		errs, ok := err.(bergamot.ValidationErrors)
+		if !ok {
+			t.Errorf("%d - %s -- expected validation errors, got: %v", i, test.Name, err)
+			continue
+		}
+		if len(errs) != len(test.Expected) {
+			t.Errorf("%d - %s -- expected %d errors got %d: %v", i, test.Name, len(test.Expected), len(errs), errs)
+			continue
+		}
+		for j, key := range test.Expected {
+			if errs[j].Key != key {
+				t.Errorf("%d - %s -- expected key %s got %s", i, test.Name, key, errs[j].Key)
+			}
+		}
+	}
+}
+
+type fakeDatasource struct {
+	host string
+}
+
+func (fakeDatasource) Kind() string { return "fake" }
+
+func TestRegisterDatasource(t *testing.T) {
+	bergamot.RegisterDatasource("fake", func(name string, config *viper.Viper) (bergamot.Datasource, error) {
+		return fakeDatasource{host: config.GetString("host")}, nil
+	})
+
+	config := viper.New()
+	config.SetConfigType("yaml")
+	if err := config.ReadConfig(bytes.NewBufferString("datasources:\n  producer:\n    type: fake\n    host: kafka\n")); err != nil {
+		t.Fatalf("unexpected error reading config: %v", err)
+	}
+	ds, err := bergamot.DefaultParser{}.GetDatasources(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fake, ok := ds["producer"].(fakeDatasource)
+	if !ok || fake.Kind() != "fake" || fake.host != "kafka" {
+		t.Errorf("expected fake datasource with host kafka, got: %#v", ds["producer"])
+	}
+}
+
+func TestAuthConfigRoles(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Token    string
+		User     string
+		Expected int
+	}
+
+	table := []TestCase{
+		{"token with role", "t1", "", 200},
+		{"token without role", "t2", "", 403},
+		{"basic user with role", "", "alice", 200},
+		{"basic user without role", "", "bob", 403},
+	}
+
+	config := viper.New()
+	config.SetConfigType("yaml")
+	content := "servers:\n  http:\n    port: 80\n    auth:\n" +
+		"      tokens:\n        admin: t1\n        viewer: t2\n" +
+		"      basic_users:\n        alice: pw\n        bob: pw\n" +
+		"      roles:\n        admin: [admin]\n        alice: [admin]\n" +
+		"    policies:\n      - path: /admin\n        roles: [admin]\n"
+	if err := config.ReadConfig(bytes.NewBufferString(content)); err != nil {
+		t.Fatalf("unexpected error reading config: %v", err)
+	}
+	servers, err := bergamot.DefaultParser{}.GetServers(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	server := servers["http"].(*bergamot.HTTPServer)
+	server.GetApp().Get("/admin", func(ctx mux.Context) { ctx.WriteString("ok") })
+	server.Boot()
+
+	for i, test := range table {
+		request := httptest.NewRequest("GET", "/admin", nil)
+		if test.Token != "" {
+			request.Header.Set("Authorization", "Bearer "+test.Token)
+		} else {
+			request.SetBasicAuth(test.User, "pw")
+		}
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != test.Expected {
+			t.Errorf("%d - %s -- expected status %d got %d: %s", i, test.Name, test.Expected, recorder.Code, recorder.Body.String())
+		}
+	}
+}
+
+func TestRateLimitByUser(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Token    string
+		Expected int
+	}
+
+	table := []TestCase{
+		{"first user", "t1", 200},
+		{"second user same IP", "t2", 200},
+		{"first user over the limit", "t1", 429},
+		{"anonymous by IP", "", 200},
+	}
+
+	config := viper.New()
+	config.SetConfigType("yaml")
+	content := "servers:\n  http:\n    port: 80\n    auth:\n" +
+		"      tokens:\n        alice: t1\n        bob: t2\n" +
+		"    rate_limit:\n      limit: 1\n      window: 1m\n      key: user\n"
+	if err := config.ReadConfig(bytes.NewBufferString(content)); err != nil {
+		t.Fatalf("unexpected error reading config: %v", err)
+	}
+	servers, err := bergamot.DefaultParser{}.GetServers(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	server := servers["http"].(*bergamot.HTTPServer)
+	server.GetApp().Get("/users", func(ctx mux.Context) { ctx.WriteString("ok") })
+	server.Boot()
+
+	for i, test := range table {
+		request := httptest.NewRequest("GET", "/users", nil)
+		request.RemoteAddr = "192.0.2.1:1234"
+		if test.Token != "" {
+			request.Header.Set("Authorization", "Bearer "+test.Token)
+		}
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != test.Expected {
+			t.Errorf("%d - %s -- expected status %d got %d: %s", i, test.Name, test.Expected, recorder.Code, recorder.Body.String())
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/alauda/bergamot/errors"
+)
+
+// Source used as source of authentication errors
+const Source = "auth"
+
+// ErrNoCredentials returned by authenticators when the request
+// does not carry credentials they can handle
+var ErrNoCredentials = NewUnauthorizedError("Authentication credentials were not provided.")
+
+// User authenticated user stored in the request context
+type User struct {
+	Name        string                 `json:"name"`
+	Roles       []string               `json:"roles,omitempty"`
+	Permissions []string               `json:"permissions,omitempty"`
+	Claims      map[string]interface{} `json:"claims,omitempty"`
+}
+
+// HasRole returns true if the user has the role
+func (u *User) HasRole(role string) bool {
+	return u != nil && contains(u.Roles, role)
+}
+
+// HasPermission returns true if the user has the permission
+func (u *User) HasPermission(permission string) bool {
+	return u != nil && contains(u.Permissions, permission)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator authenticates a request returning its user
+// should return ErrNoCredentials if the request has no credentials
+// of the supported type and an unauthorized error if they are invalid
+type Authenticator interface {
+	Authenticate(r *http.Request) (*User, error)
+}
+
+// AuthenticatorFunc function that implements Authenticator
+type AuthenticatorFunc func(r *http.Request) (*User, error)
+
+// Authenticate calls the function
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (*User, error) {
+	return f(r)
+}
+
+// Chain authenticator that tries each authenticator in order
+// until one of them authenticates the request
+type Chain []Authenticator
+
+// Authenticate returns the user of the first authenticator that succeeds.
+// If none does returns the first error other than ErrNoCredentials
+func (c Chain) Authenticate(r *http.Request) (*User, error) {
+	var first error
+	for _, authenticator := range c {
+		user, err := authenticator.Authenticate(r)
+		if err == nil {
+			return user, nil
+		}
+		if first == nil && err != ErrNoCredentials {
+			first = err
+		}
+	}
+	if first != nil {
+		return nil, first
+	}
+	return nil, ErrNoCredentials
+}
+
+// NewUnauthorizedError returns an unauthorized error with the given message
+func NewUnauthorizedError(format string, args ...interface{}) *errors.AlaudaError {
+	return errors.New(Source, errors.ErrorCodeUnauthorized).SetMessage(format, args...)
+}
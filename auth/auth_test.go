@@ -0,0 +1,114 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alauda/bergamot/auth"
+	"github.com/alauda/bergamot/errors"
+)
+
+func encodeSegment(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func signHS256(secret string, claims map[string]interface{}) string {
+	signed := encodeSegment(map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encodeSegment(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	signed := encodeSegment(map[string]string{"alg": "RS256", "kid": kid}) + "." + encodeSegment(claims)
+	hashed := sha256.Sum256([]byte(signed))
+	signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestChainAuthenticate(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Header   string
+		Expected string
+		Err      bool
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %v", err)
+	}
+	file, err := ioutil.TempFile("", "jwks")
+	if err != nil {
+		t.Fatalf("unexpected error creating file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	json.NewEncoder(file).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "main",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+	file.Close()
+
+	jwt, err := auth.NewJWTAuthenticator(auth.JWTConfig{
+		Secret:   []byte("secret"),
+		JWKSFile: file.Name(),
+		Audience: "bergamot",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	chain := auth.Chain{
+		auth.NewBearerAuthenticator(map[string]*auth.User{"static-token": {Name: "robot"}}),
+		auth.NewBasicAuthenticator(map[string]auth.BasicUser{"admin": {Password: "pass"}}),
+		jwt,
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	table := []TestCase{
+		{"no credentials", "", "", true},
+		{"static token", "Bearer static-token", "robot", false},
+		{"invalid static token", "Bearer other-token", "", true},
+		{"basic", "Basic " + base64.StdEncoding.EncodeToString([]byte("admin:pass")), "admin", false},
+		{"invalid basic", "Basic " + base64.StdEncoding.EncodeToString([]byte("admin:wrong")), "", true},
+		{"hs256", "Bearer " + signHS256("secret", map[string]interface{}{"sub": "user", "aud": "bergamot", "exp": exp}), "user", false},
+		{"hs256 wrong secret", "Bearer " + signHS256("other", map[string]interface{}{"sub": "user", "aud": "bergamot", "exp": exp}), "", true},
+		{"hs256 expired", "Bearer " + signHS256("secret", map[string]interface{}{"sub": "user", "aud": "bergamot", "exp": 1}), "", true},
+		{"hs256 wrong audience", "Bearer " + signHS256("secret", map[string]interface{}{"sub": "user", "aud": "other"}), "", true},
+		{"rs256", "Bearer " + signRS256(key, "main", map[string]interface{}{"sub": "service", "aud": []string{"bergamot"}}), "service", false},
+		{"rs256 unknown key", "Bearer " + signRS256(key, "other", map[string]interface{}{"sub": "service", "aud": "bergamot"}), "", true},
+	}
+
+	for i, test := range table {
+		request := httptest.NewRequest("GET", "/", nil)
+		if test.Header != "" {
+			request.Header.Set("Authorization", test.Header)
+		}
+		user, err := chain.Authenticate(request)
+		if test.Err {
+			alErr, ok := err.(*errors.AlaudaError)
+			if !ok || alErr.Code != errors.ErrorCodeUnauthorized || alErr.StatusCode != 401 {
+				t.Errorf("%d - %s -- expected unauthorized error got %v", i, test.Name, err)
+			}
+			continue
+		}
+		if err != nil || user.Name != test.Expected {
+			t.Errorf("%d - %s -- expected user %s got %v, %v", i, test.Name, test.Expected, user, err)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+)
+
+// JWT algorithms
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+)
+
+// JWTConfig configuration for JWTAuthenticator
+type JWTConfig struct {
+	// Secret shared secret to verify HS256 tokens
+	Secret []byte
+	// JWKSFile local JSON Web Key Set file with the RSA keys to verify RS256 tokens
+	JWKSFile string
+	// Issuer and Audience are verified when set
+	Issuer   string
+	Audience string
+	// Leeway accepted clock skew when verifying exp and nbf
+	Leeway time.Duration
+	// RolesClaim and PermissionsClaim claims copied to the user
+	// default to roles and permissions
+	RolesClaim       string
+	PermissionsClaim string
+}
+
+// JWTAuthenticator authenticates Bearer JSON Web Tokens
+// signed using HS256 or RS256
+type JWTAuthenticator struct {
+	config JWTConfig
+	keys   map[string]*rsa.PublicKey
+	now    func() time.Time
+}
+
+// NewJWTAuthenticator constructor for JWTAuthenticator
+// returns an error if the JWKS file can not be read
+func NewJWTAuthenticator(config JWTConfig) (*JWTAuthenticator, error) {
+	if config.RolesClaim == "" {
+		config.RolesClaim = "roles"
+	}
+	if config.PermissionsClaim == "" {
+		config.PermissionsClaim = "permissions"
+	}
+	j := &JWTAuthenticator{
+		config: config,
+		keys:   map[string]*rsa.PublicKey{},
+		now:    time.Now,
+	}
+	if config.JWKSFile != "" {
+		keys, err := readJWKS(config.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		j.keys = keys
+	}
+	if len(config.Secret) == 0 && len(j.keys) == 0 {
+		return nil, fmt.Errorf("jwt: a secret or a JWKS file is required")
+	}
+	return j, nil
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// Authenticate verifies the Bearer token in the Authorization header
+func (j *JWTAuthenticator) Authenticate(r *http.Request) (*User, error) {
+	token, ok := getBearerToken(r)
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		// not a JWT, other authenticators may handle it
+		return nil, ErrNoCredentials
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, NewUnauthorizedError("Invalid token header.")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, NewUnauthorizedError("Invalid token signature.")
+	}
+	if err := j.verify(header, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+	claims := map[string]interface{}{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, NewUnauthorizedError("Invalid token claims.")
+	}
+	if err := j.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return &User{
+		Name:        cast.ToString(claims["sub"]),
+		Roles:       cast.ToStringSlice(claims[j.config.RolesClaim]),
+		Permissions: cast.ToStringSlice(claims[j.config.PermissionsClaim]),
+		Claims:      claims,
+	}, nil
+}
+
+// verify verifies the signature using the algorithm of the header
+func (j *JWTAuthenticator) verify(header jwtHeader, signed string, signature []byte) error {
+	switch header.Algorithm {
+	case AlgorithmHS256:
+		if len(j.config.Secret) == 0 {
+			break
+		}
+		mac := hmac.New(sha256.New, j.config.Secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return NewUnauthorizedError("Invalid token signature.")
+		}
+		return nil
+	case AlgorithmRS256:
+		key := j.getKey(header.KeyID)
+		if key == nil {
+			return NewUnauthorizedError("Unknown token key.")
+		}
+		hashed := sha256.Sum256([]byte(signed))
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signature) != nil {
+			return NewUnauthorizedError("Invalid token signature.")
+		}
+		return nil
+	}
+	return NewUnauthorizedError("Unsupported token algorithm %q.", header.Algorithm)
+}
+
+// getKey returns the key with the given id
+// tokens without id are accepted when there is only one key
+func (j *JWTAuthenticator) getKey(kid string) *rsa.PublicKey {
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key
+		}
+	}
+	return j.keys[kid]
+}
+
+// validateClaims validates the time, issuer and audience claims
+func (j *JWTAuthenticator) validateClaims(claims map[string]interface{}) error {
+	now := j.now()
+	if exp, ok := claims["exp"]; ok && now.After(time.Unix(cast.ToInt64(exp), 0).Add(j.config.Leeway)) {
+		return NewUnauthorizedError("Token is expired.")
+	}
+	if nbf, ok := claims["nbf"]; ok && now.Add(j.config.Leeway).Before(time.Unix(cast.ToInt64(nbf), 0)) {
+		return NewUnauthorizedError("Token is not valid yet.")
+	}
+	if j.config.Issuer != "" && cast.ToString(claims["iss"]) != j.config.Issuer {
+		return NewUnauthorizedError("Invalid token issuer.")
+	}
+	if j.config.Audience != "" {
+		var audiences []string
+		switch aud := claims["aud"].(type) {
+		case string:
+			audiences = []string{aud}
+		default:
+			audiences = cast.ToStringSlice(aud)
+		}
+		if !contains(audiences, j.config.Audience) {
+			return NewUnauthorizedError("Invalid token audience.")
+		}
+	}
+	return nil
+}
+
+func decodeSegment(segment string, value interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
+
+type jwks struct {
+	Keys []struct {
+		KeyType string `json:"kty"`
+		KeyID   string `json:"kid"`
+		Use     string `json:"use"`
+		N       string `json:"n"`
+		E       string `json:"e"`
+	} `json:"keys"`
+}
+
+// readJWKS reads the RSA signing keys of a JSON Web Key Set file
+func readJWKS(file string) (map[string]*rsa.PublicKey, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var set jwks
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("jwt: invalid JWKS file %s: %v", file, err)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.KeyType != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: invalid modulus for key %q: %v", k.KeyID, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: invalid exponent for key %q: %v", k.KeyID, err)
+		}
+		keys[k.KeyID] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
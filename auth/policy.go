@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/alauda/bergamot/contexts"
+	"github.com/alauda/bergamot/errors"
+
+	"github.com/spf13/cast"
+)
+
+// Policy requirements to access the routes under a path
+type Policy struct {
+	// Path route template or prefix of route templates, e.g. /v1/users
+	Path string
+	// Methods HTTP methods the policy applies to, all methods if empty
+	Methods []string
+	// Roles the user needs any of these roles
+	Roles []string
+	// Permissions the user needs all of these permissions
+	Permissions []string
+}
+
+// Matches returns true if the policy applies to the method and route template
+func (p Policy) Matches(method, route string) bool {
+	path := strings.TrimSuffix(p.Path, "/")
+	if route != path && !strings.HasPrefix(route, path+"/") {
+		return false
+	}
+	if len(p.Methods) == 0 {
+		return true
+	}
+	for _, m := range p.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePolicies parses a list of policies from configuration values
+// each item should be a map with path, methods, roles and permissions keys
+func ParsePolicies(value interface{}) ([]Policy, error) {
+	items, err := cast.ToSliceE(value)
+	if err != nil {
+		return nil, fmt.Errorf("policies: expected list: %v", err)
+	}
+	policies := make([]Policy, 0, len(items))
+	for i, item := range items {
+		values, err := cast.ToStringMapE(item)
+		if err != nil {
+			return nil, fmt.Errorf("policies[%d]: expected map: %v", i, err)
+		}
+		policy := Policy{
+			Path:        cast.ToString(values["path"]),
+			Methods:     cast.ToStringSlice(values["methods"]),
+			Roles:       cast.ToStringSlice(values["roles"]),
+			Permissions: cast.ToStringSlice(values["permissions"]),
+		}
+		if policy.Path == "" {
+			return nil, fmt.Errorf("policies[%d].path: required field is missing", i)
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// RoleHolder user with roles and permissions
+// implemented by User, custom user types can implement it
+// to use the default authorizer
+type RoleHolder interface {
+	HasRole(role string) bool
+	HasPermission(permission string) bool
+}
+
+// Authorizer verifies the user in the context satisfies a policy
+// should return a permission_denied error if it does not
+type Authorizer interface {
+	Authorize(ctx context.Context, policy Policy) error
+}
+
+// AuthorizerFunc function that implements Authorizer
+type AuthorizerFunc func(ctx context.Context, policy Policy) error
+
+// Authorize calls the function
+func (f AuthorizerFunc) Authorize(ctx context.Context, policy Policy) error {
+	return f(ctx, policy)
+}
+
+// RoleAuthorizer default authorizer for RoleHolder users
+type RoleAuthorizer struct{}
+
+// NewRoleAuthorizer constructor for RoleAuthorizer
+func NewRoleAuthorizer() RoleAuthorizer {
+	return RoleAuthorizer{}
+}
+
+// Authorize verifies the user has any of the roles and all the permissions of the policy
+func (RoleAuthorizer) Authorize(ctx context.Context, policy Policy) error {
+	user, ok := contexts.GetUser(ctx).(RoleHolder)
+	if !ok || user == nil {
+		return ErrNoCredentials
+	}
+	if len(policy.Roles) > 0 {
+		allowed := false
+		for _, role := range policy.Roles {
+			if user.HasRole(role) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return NewPermissionDeniedError("One of the roles %s is required.", strings.Join(policy.Roles, ", "))
+		}
+	}
+	for _, permission := range policy.Permissions {
+		if !user.HasPermission(permission) {
+			return NewPermissionDeniedError("Permission %s is required.", permission)
+		}
+	}
+	return nil
+}
+
+// NewPermissionDeniedError returns a permission denied error with the given message
+func NewPermissionDeniedError(format string, args ...interface{}) *errors.AlaudaError {
+	return errors.New(Source, errors.ErrorCodePermissionDenied).SetMessage(format, args...)
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// getBearerToken returns the token of a Bearer Authorization header
+func getBearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return "", false
+	}
+	return strings.TrimSpace(header[7:]), true
+}
+
+// BearerAuthenticator authenticates static bearer tokens
+type BearerAuthenticator struct {
+	tokens map[string]*User
+}
+
+// NewBearerAuthenticator constructor for BearerAuthenticator
+// tokens maps each token to its user
+func NewBearerAuthenticator(tokens map[string]*User) *BearerAuthenticator {
+	return &BearerAuthenticator{tokens: tokens}
+}
+
+// Authenticate checks the Bearer token in the Authorization header
+func (b *BearerAuthenticator) Authenticate(r *http.Request) (*User, error) {
+	token, ok := getBearerToken(r)
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	// comparing all tokens to keep a constant time
+	var user *User
+	for t, u := range b.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			user = u
+		}
+	}
+	if user == nil {
+		return nil, NewUnauthorizedError("Invalid token.")
+	}
+	return user, nil
+}
+
+// BasicUser user credentials for BasicAuthenticator
+type BasicUser struct {
+	Password    string
+	Roles       []string
+	Permissions []string
+}
+
+// BasicAuthenticator authenticates HTTP Basic credentials
+type BasicAuthenticator struct {
+	users map[string]BasicUser
+}
+
+// NewBasicAuthenticator constructor for BasicAuthenticator
+// users maps each username to its credentials
+func NewBasicAuthenticator(users map[string]BasicUser) *BasicAuthenticator {
+	return &BasicAuthenticator{users: users}
+}
+
+// Authenticate checks the Basic credentials in the Authorization header
+func (b *BasicAuthenticator) Authenticate(r *http.Request) (*User, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	basic, ok := b.users[username]
+	if !ok || subtle.ConstantTimeCompare([]byte(basic.Password), []byte(password)) != 1 {
+		return nil, NewUnauthorizedError("Invalid username or password.")
+	}
+	return &User{
+		Name:        username,
+		Roles:       basic.Roles,
+		Permissions: basic.Permissions,
+	}, nil
+}
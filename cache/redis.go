@@ -2,6 +2,7 @@ package cache
 
 import (
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/alauda/bergamot/diagnose"
@@ -81,6 +82,27 @@ func (r *RedisCache) Writer() aredis.Commander {
 	return r.Write
 }
 
+// Close closes the reader and writer connections
+func (r *RedisCache) Close() error {
+	err := closeClient(r.Read)
+	if r.Write != r.Read {
+		if writeErr := closeClient(r.Write); err == nil {
+			err = writeErr
+		}
+	}
+	return err
+}
+
+func closeClient(client *aredis.Client) error {
+	if client == nil {
+		return nil
+	}
+	if closer, ok := client.GetClient().(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
 // Diagnose start diagnose check
 // http://confluence.alaudatech.com/pages/viewpage.action?pageId=14123161
 func (r *RedisCache) Diagnose() diagnose.ComponentReport {
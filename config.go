@@ -0,0 +1,60 @@
+package bergamot
+
+import (
+	"fmt"
+)
+
+// ValidationError configuration error for a specific key
+type ValidationError struct {
+	Key     string
+	Message string
+}
+
+// NewValidationError constructor function for ValidationError
+func NewValidationError(key, format string, args ...interface{}) *ValidationError {
+	return &ValidationError{
+		Key:     key,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// Error satisfies the error interface
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Key, e.Message)
+}
+
+// ValidationErrors collection of configuration errors
+// used to report all the problems at once
+type ValidationErrors []*ValidationError
+
+// Error satisfies the error interface
+func (e ValidationErrors) Error() string {
+	errs := make(Errors, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs.Error()
+}
+
+// Add adds errors to the collection
+// accepts *ValidationError and ValidationErrors and
+// returns false if the error is of any other type
+func (e *ValidationErrors) Add(err error) bool {
+	switch val := err.(type) {
+	case *ValidationError:
+		*e = append(*e, val)
+	case ValidationErrors:
+		*e = append(*e, val...)
+	default:
+		return false
+	}
+	return true
+}
+
+// Err returns the collection as an error or nil if empty
+func (e ValidationErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
@@ -1,6 +1,10 @@
 package contexts
 
-import "context"
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+)
 
 // ContextUserKey user key
 type ContextUserKey struct{}
@@ -126,3 +130,34 @@ func GetRequestID(ctx context.Context) (requestID string) {
 	}
 	return
 }
+
+// MaxRequestIDLength maximum length of a request ID received from clients
+const MaxRequestIDLength = 128
+
+// IsValidRequestID returns true if the request ID received from clients
+// is not empty, at most MaxRequestIDLength long and only has
+// letters, digits, dots, underscores or hyphens.
+// Used to avoid header and log injection
+func IsValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > MaxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// NewRequestID generates a random request ID in UUID v4 format
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,59 @@
+package bergamot
+
+import (
+	"github.com/alauda/bergamot/diagnose"
+	"github.com/alauda/bergamot/http"
+)
+
+const defaultDiagnosePath = "/_diagnose"
+
+// DiagnoseMounter a server that can serve the health report of the app
+type DiagnoseMounter interface {
+	MountDiagnoser(path string, checker *diagnose.HealthChecker)
+}
+
+// healthCheckerSetter a server that reports its health using the app health checker
+type healthCheckerSetter interface {
+	SetHealthChecker(checker *diagnose.HealthChecker)
+}
+
+// setupDiagnose adds all datasources that implement diagnose.Component
+// to the app health checker, sets it on servers that report their health
+// and mounts it in all servers on the given path.
+// An empty path will not mount the checker
+func (a *App) setupDiagnose(path string) error {
+	checker, err := diagnose.New()
+	if err != nil {
+		return err
+	}
+	for _, name := range a.getDatasourceNames() {
+		if component, ok := a.Datasources[name].(diagnose.Component); ok {
+			checker.Add(component)
+		}
+	}
+	a.HealthChecker = checker
+	for _, name := range a.getServerNames() {
+		if server, ok := a.Servers[name].(healthCheckerSetter); ok {
+			server.SetHealthChecker(checker)
+		}
+	}
+	if path == "" {
+		return nil
+	}
+	for _, name := range a.getServerNames() {
+		if mounter, ok := a.Servers[name].(DiagnoseMounter); ok {
+			mounter.MountDiagnoser(path, checker)
+		}
+	}
+	return nil
+}
+
+// MountDiagnoser adds the diagnose endpoint on the given path
+func (s *HTTPServer) MountDiagnoser(path string, checker *diagnose.HealthChecker) {
+	s.AddEndpoint(path, http.NewDiagnoser(checker))
+}
+
+// MountDiagnoser adds the diagnose endpoint on the given path of the HTTP1.1 healthcheck server
+func (s *GRPCServer) MountDiagnoser(path string, checker *diagnose.HealthChecker) {
+	s.HandleHTTP(path, checker)
+}
@@ -2,6 +2,7 @@ package diagnose
 
 import (
 	"fmt"
+	"net/http"
 	"time"
 
 	"encoding/json"
@@ -41,14 +42,17 @@ func (h *HealthChecker) Check() HealthReport {
 		return *report
 	}
 	wait := sync.WaitGroup{}
+	lock := sync.Mutex{}
 	for _, c := range h.Components {
 		wait.Add(1)
 		go func(component Component) {
 			diagnose := component.Diagnose()
+			lock.Lock()
 			if diagnose.Status == StatusError && report.Status != StatusError {
 				report.Status = StatusError
 			}
 			report.Add(diagnose)
+			lock.Unlock()
 			wait.Done()
 		}(c)
 	}
@@ -56,6 +60,14 @@ func (h *HealthChecker) Check() HealthReport {
 	return *report
 }
 
+// ServeHTTP serves the health report as JSON
+// used to mount the checker on a net/http server
+func (h *HealthChecker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.Check())
+}
+
 // HealthStatus type to create health status
 type HealthStatus string
 
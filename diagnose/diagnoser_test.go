@@ -1,6 +1,9 @@
 package diagnose_test
 
 import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 	"time"
@@ -123,3 +126,33 @@ func TestComponentReportMarshal(t *testing.T) {
 
 	}
 }
+
+type failingComponent struct{}
+
+func (failingComponent) Diagnose() diagnose.ComponentReport {
+	report := diagnose.NewReport("failing")
+	report.Check(errors.New("down"), "Failed", "Fix it")
+	return *report
+}
+
+func TestHealthCheckerServeHTTP(t *testing.T) {
+	checker, _ := diagnose.New()
+	checker.Add(failingComponent{})
+
+	recorder := httptest.NewRecorder()
+	checker.ServeHTTP(recorder, httptest.NewRequest("GET", "/_diagnose", nil))
+
+	var report struct {
+		Status  diagnose.HealthStatus `json:"status"`
+		Details []struct {
+			Name   string                `json:"name"`
+			Status diagnose.HealthStatus `json:"status"`
+		} `json:"details"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid json response: %v", err)
+	}
+	if report.Status != diagnose.StatusError || len(report.Details) != 1 || report.Details[0].Name != "failing" {
+		t.Errorf("unexpected report: %s", recorder.Body.String())
+	}
+}
@@ -36,6 +36,8 @@ const (
 	ErrorCodeNotImplemented = "not_implemented"
 	// ErrorCodeUnauthorized when user is not authorized
 	ErrorCodeUnauthorized = "unauthorized"
+	// ErrorCodeTooManyRequests when the client exceeded the rate limit
+	ErrorCodeTooManyRequests = "too_many_requests"
 	// ErrorCodeNotFound when is not found
 	ErrorCodeNotFound = "not_found"
 	// ErrorCodeElasticSearchError when using a elastic search and it returned an error
@@ -55,6 +57,8 @@ var (
 		ErrorCodePermissionDenied:      NewErrorStatusMessage("Current user has no permission to perform the action.", 403),
 		ErrorCodeResourceStateConflict: NewErrorStatusMessage("The posted resource already existed.", 409),
 		ErrorCodeNotImplemented:        NewErrorStatusMessage("Method not implemented", 501),
+		ErrorCodeUnauthorized:          NewErrorStatusMessage("Authentication credentials were not provided or are invalid.", 401),
+		ErrorCodeTooManyRequests:       NewErrorStatusMessage("Too many requests, rate limit exceeded.", 429),
 		ErrorCodeElasticSearchError:    NewErrorStatusMessage("Elastic search error.", 500),
 		ErrorCodeDatabaseError:         NewErrorStatusMessage("Database error.", 500),
 	}
@@ -140,11 +144,7 @@ func (h *AlaudaError) SetSource(source string) *AlaudaError {
 // AddFieldError adds a field error and return itself
 func (h *AlaudaError) AddFieldError(field string, message ...string) *AlaudaError {
 	h.initializeFields()
-	if value, ok := h.Fields[0][field]; ok {
-		value = append(value, message...)
-		return h
-	}
-	h.Fields[0][field] = message
+	h.Fields[0][field] = append(h.Fields[0][field], message...)
 	return h
 }
 
@@ -0,0 +1,155 @@
+package errors
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	// GRPCCodeList gRPC codes for the default error codes
+	// codes not present will be mapped using the HTTP status code
+	GRPCCodeList = map[Code]codes.Code{
+		ErrorCodeResourceNotFound:      codes.NotFound,
+		ErrorCodeInvalidArgs:           codes.InvalidArgument,
+		ErrorCodeBadRequest:            codes.InvalidArgument,
+		ErrorCodeResourceAlreadyExists: codes.AlreadyExists,
+		ErrorCodeUnknownIssue:          codes.Unknown,
+		ErrorCodePermissionDenied:      codes.PermissionDenied,
+		ErrorCodeResourceStateConflict: codes.FailedPrecondition,
+		ErrorCodeNotImplemented:        codes.Unimplemented,
+		ErrorCodeUnauthorized:          codes.Unauthenticated,
+		ErrorCodeNotFound:              codes.NotFound,
+		ErrorCodeTooManyRequests:       codes.ResourceExhausted,
+		ErrorCodeElasticSearchError:    codes.Internal,
+		ErrorCodeDatabaseError:         codes.Internal,
+	}
+	// grpcStatusList HTTP status codes for gRPC codes
+	// used for errors that were not generated with ToGRPCStatus
+	grpcStatusList = map[codes.Code]int{
+		codes.InvalidArgument:    400,
+		codes.OutOfRange:         400,
+		codes.Unauthenticated:    401,
+		codes.PermissionDenied:   403,
+		codes.NotFound:           404,
+		codes.AlreadyExists:      409,
+		codes.Aborted:            409,
+		codes.FailedPrecondition: 409,
+		codes.ResourceExhausted:  429,
+		codes.Canceled:           499,
+		codes.Unimplemented:      501,
+		codes.Unavailable:        503,
+		codes.DeadlineExceeded:   504,
+	}
+	// grpcErrorCodeList error codes for gRPC codes
+	// used for errors that were not generated with ToGRPCStatus
+	grpcErrorCodeList = map[codes.Code]Code{
+		codes.InvalidArgument:    ErrorCodeInvalidArgs,
+		codes.Unauthenticated:    ErrorCodeUnauthorized,
+		codes.PermissionDenied:   ErrorCodePermissionDenied,
+		codes.NotFound:           ErrorCodeResourceNotFound,
+		codes.AlreadyExists:      ErrorCodeResourceAlreadyExists,
+		codes.FailedPrecondition: ErrorCodeResourceStateConflict,
+		codes.Unimplemented:      ErrorCodeNotImplemented,
+		codes.ResourceExhausted:  ErrorCodeTooManyRequests,
+	}
+)
+
+// grpcError format used to send an AlaudaError as the gRPC error description
+type grpcError struct {
+	Source     string                `json:"source"`
+	Message    string                `json:"message"`
+	Code       Code                  `json:"code"`
+	Fields     []map[string][]string `json:"fields,omitempty"`
+	StatusCode int                   `json:"status_code"`
+}
+
+// GetGRPCCode returns the gRPC code for the error code
+// falling back to the HTTP status code if not found in GRPCCodeList
+func (h *AlaudaError) GetGRPCCode() codes.Code {
+	if code, ok := GRPCCodeList[h.Code]; ok {
+		return code
+	}
+	switch h.StatusCode {
+	case 400:
+		return codes.InvalidArgument
+	case 401:
+		return codes.Unauthenticated
+	case 403:
+		return codes.PermissionDenied
+	case 404:
+		return codes.NotFound
+	case 409:
+		return codes.AlreadyExists
+	case 429:
+		return codes.ResourceExhausted
+	case 501:
+		return codes.Unimplemented
+	case 503:
+		return codes.Unavailable
+	case 504:
+		return codes.DeadlineExceeded
+	}
+	return codes.Unknown
+}
+
+// ToGRPCStatus converts an error into a gRPC error
+// an AlaudaError will keep its source, message and fields
+// in the description to be restored using FromGRPCStatus.
+// Other errors are returned as they are
+func ToGRPCStatus(err error) error {
+	alErr, ok := err.(*AlaudaError)
+	if !ok || alErr == nil {
+		return err
+	}
+	desc, jsonErr := json.Marshal(grpcError{
+		Source:     alErr.Source,
+		Message:    alErr.Message,
+		Code:       alErr.Code,
+		Fields:     alErr.Fields,
+		StatusCode: alErr.StatusCode,
+	})
+	if jsonErr != nil {
+		return grpc.Errorf(alErr.GetGRPCCode(), "%s", alErr.Error())
+	}
+	return grpc.Errorf(alErr.GetGRPCCode(), "%s", desc)
+}
+
+// FromGRPCStatus converts a gRPC error into an AlaudaError
+// errors generated using ToGRPCStatus are restored completely,
+// any other error will use the given source and a code based on its gRPC code
+func FromGRPCStatus(source string, err error) *AlaudaError {
+	if err == nil {
+		return nil
+	}
+	if alErr, ok := err.(*AlaudaError); ok {
+		return alErr
+	}
+	desc := grpc.ErrorDesc(err)
+	decoded := grpcError{}
+	if json.Unmarshal([]byte(desc), &decoded) == nil && decoded.Code != "" {
+		return &AlaudaError{
+			Source:     decoded.Source,
+			Message:    decoded.Message,
+			Code:       decoded.Code,
+			Fields:     decoded.Fields,
+			StatusCode: decoded.StatusCode,
+		}
+	}
+	code := grpc.Code(err)
+	errorCode, ok := grpcErrorCodeList[code]
+	if !ok {
+		errorCode = ErrorCodeUnknownIssue
+	}
+	status, ok := grpcStatusList[code]
+	if !ok {
+		status = ErrorMessageList[ErrorCodeUnknownIssue].StatusCode
+	}
+	return &AlaudaError{
+		Source:     source,
+		Code:       errorCode,
+		Message:    desc,
+		StatusCode: status,
+	}
+}
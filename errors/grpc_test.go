@@ -0,0 +1,59 @@
+package errors_test
+
+import (
+	goerrors "errors"
+	"reflect"
+	"testing"
+
+	"github.com/alauda/bergamot/errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestGRPCStatus(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Err      error
+		Code     codes.Code
+		Expected *errors.AlaudaError
+	}
+
+	table := []TestCase{
+		{
+			"alauda error with fields",
+			errors.New("users", errors.ErrorCodeInvalidArgs).AddFieldError("name", "required"),
+			codes.InvalidArgument,
+			errors.New("users", errors.ErrorCodeInvalidArgs).AddFieldError("name", "required"),
+		},
+		{
+			"custom code",
+			errors.New("users", "quota_exceeded").SetMessage("too many users"),
+			codes.Unknown,
+			errors.New("users", "quota_exceeded").SetMessage("too many users"),
+		},
+		{
+			"plain gRPC error",
+			grpc.Errorf(codes.NotFound, "user not found"),
+			codes.NotFound,
+			errors.New("client", errors.ErrorCodeResourceNotFound).SetMessage("user not found"),
+		},
+		{
+			"common error",
+			goerrors.New("connection refused"),
+			codes.Unknown,
+			errors.New("client", errors.ErrorCodeUnknownIssue).SetMessage("connection refused"),
+		},
+	}
+
+	for i, test := range table {
+		err := errors.ToGRPCStatus(test.Err)
+		if grpc.Code(err) != test.Code {
+			t.Errorf("%d - %s -- expected code %v got %v", i, test.Name, test.Code, grpc.Code(err))
+		}
+		result := errors.FromGRPCStatus("client", err)
+		if !reflect.DeepEqual(result, test.Expected) {
+			t.Errorf("%d - %s -- expected %#v got %#v", i, test.Name, test.Expected, result)
+		}
+	}
+}
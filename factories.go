@@ -0,0 +1,437 @@
+package bergamot
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/alauda/bergamot/auth"
+	"github.com/alauda/bergamot/cache"
+	"github.com/alauda/bergamot/db"
+	"github.com/alauda/bergamot/diagnose"
+	"github.com/alauda/bergamot/elasticsearch"
+	"github.com/alauda/bergamot/grpc"
+	"github.com/alauda/bergamot/http"
+	"github.com/alauda/bergamot/log"
+	"github.com/alauda/bergamot/metrics"
+	"github.com/alauda/bergamot/ratelimit"
+	"github.com/alauda/bergamot/utils"
+
+	"github.com/spf13/viper"
+	goqu "gopkg.in/doug-martin/goqu.v4"
+)
+
+func init() {
+	RegisterDatasource(db.MySQL.String(), newSQLFactory(db.MySQL), sqlFields...)
+	RegisterDatasource(db.Postgres.String(), newSQLFactory(db.Postgres), sqlFields...)
+	RegisterDatasource(KindRedis, newRedis, redisFields...)
+	RegisterDatasource(KindStatsd, newStatsd, statsdFields...)
+	RegisterDatasource(KindElasticSearch, newElasticSearch, elasticSearchFields...)
+	RegisterServer(KindHTTP, newHTTPServer, httpFields...)
+	RegisterServer(KindGRPC, newGRPCServer, grpcFields...)
+}
+
+var (
+	sqlFields = []Field{
+		{Name: "host", Type: TypeString, Required: true},
+		{Name: "port", Type: TypeInt},
+		{Name: "database", Type: TypeString, Required: true},
+		{Name: "user", Type: TypeString},
+		{Name: "password", Type: TypeString, Secret: true},
+		{Name: "timeout", Type: TypeInt, Description: "connection timeout in seconds"},
+		{Name: "max_connections", Type: TypeInt},
+		{Name: "max_idle_connections", Type: TypeInt},
+		{Name: "conn_max_lifetime", Type: TypeInt, Description: "connection max lifetime in seconds"},
+		{Name: "params", Type: TypeMap, Description: "connection string parameters"},
+	}
+	redisConnFields = []Field{
+		{Name: "host", Type: TypeString, Required: true},
+		{Name: "port", Type: TypeInt, Required: true},
+		{Name: "db", Type: TypeInt},
+		{Name: "password", Type: TypeString, Secret: true},
+	}
+	redisFields = append(redisConnFields[:len(redisConnFields):len(redisConnFields)],
+		Field{Name: "writer", Type: TypeMap, Description: "optional writer instance", Fields: redisConnFields},
+	)
+	statsdFields = []Field{
+		{Name: "host", Type: TypeString, Required: true},
+		{Name: "port", Type: TypeInt, Required: true},
+		{Name: "enabled", Type: TypeBool, Description: "defaults to true"},
+		{Name: "buffer_size", Type: TypeInt, Description: "defaults to 100"},
+		{Name: "sample_rate", Type: TypeFloat, Description: "scales the sample rate of all metrics, defaults to 1"},
+	}
+	elasticSearchFields = []Field{
+		{Name: "endpoint", Type: TypeString, Required: true},
+		{Name: "username", Type: TypeString},
+		{Name: "password", Type: TypeString, Secret: true},
+		{Name: "retries", Type: TypeInt},
+		{Name: "health_check_timeout", Type: TypeDuration},
+	}
+	accessLogFields = []Field{
+		{Name: "format", Type: TypeString, Description: "fields, common or combined"},
+		{Name: "fields", Type: TypeList, Description: "fields logged using the fields format"},
+		{Name: "exclude_paths", Type: TypeList, Description: "paths not logged like /_ping"},
+		{Name: "success_sample_rate", Type: TypeFloat, Description: "fraction of 2xx responses logged"},
+	}
+	authFields = []Field{
+		{Name: "tokens", Type: TypeMap, Secret: true, Description: "user names mapped to static bearer tokens"},
+		{Name: "basic_users", Type: TypeMap, Secret: true, Description: "HTTP Basic user names mapped to passwords"},
+		{Name: "roles", Type: TypeMap, Description: "token and basic user names mapped to their roles"},
+		{Name: "permissions", Type: TypeMap, Description: "token and basic user names mapped to their permissions"},
+		{Name: "jwt_secret", Type: TypeString, Secret: true, Description: "secret to verify HS256 tokens"},
+		{Name: "jwks_file", Type: TypeString, Description: "JWKS file with the keys to verify RS256 tokens"},
+		{Name: "jwt_issuer", Type: TypeString},
+		{Name: "jwt_audience", Type: TypeString},
+	}
+	rateLimitFields = []Field{
+		{Name: "limit", Type: TypeInt, Required: true, Description: "requests allowed per window"},
+		{Name: "window", Type: TypeDuration, Description: "defaults to 1s"},
+		{Name: "key", Type: TypeString, Description: "ip, user or header:<name>, defaults to ip"},
+		{Name: "per_route", Type: TypeBool, Description: "limits each route separately"},
+		{Name: "trusted_proxies", Type: TypeList, Description: "IPs or CIDRs of proxies trusted to set X-Forwarded-For, defaults to the server trusted_proxies"},
+	}
+	httpFields = []Field{
+		{Name: "host", Type: TypeString},
+		{Name: "port", Type: TypeInt, Required: true},
+		{Name: "component", Type: TypeString, Description: "defaults to the app component"},
+		{Name: "add_log", Type: TypeBool},
+		{Name: "auth", Type: TypeMap, Description: "authenticators for routes using the auth middleware type", Fields: authFields},
+		{Name: "policies", Type: TypeList, Description: "roles and permissions required by route path and methods"},
+		{Name: "access_log", Type: TypeMap, Description: "logs every request after it finished", Fields: accessLogFields},
+		{Name: "rate_limit", Type: TypeMap, Description: "in-memory rate limit for all routes", Fields: rateLimitFields},
+		{Name: "add_recovery", Type: TypeBool, Description: "returns unknown_issue errors when handlers panic"},
+		{Name: "metrics", Type: TypeString, Description: "statsd datasource used by add_recovery to count panics"},
+		{Name: "add_request_id", Type: TypeBool, Description: "reads or generates a X-Request-ID for every request"},
+		{Name: "add_health_check", Type: TypeBool},
+		{Name: "add_openapi", Type: TypeBool, Description: "serves the OpenAPI document of each version at /v{n}/openapi.json"},
+		{Name: "max_read_buffer_size", Type: TypeInt},
+		{Name: "gzip", Type: TypeBool, Description: "compresses responses for clients that accept gzip"},
+		{Name: "gzip_min_size", Type: TypeInt, Description: "minimum response size to compress, defaults to 1024"},
+		{Name: "allowed_origins", Type: TypeList},
+		{Name: "trusted_proxies", Type: TypeList, Description: "IPs or CIDRs of proxies trusted to set X-Forwarded-For"},
+		{Name: "cert_file", Type: TypeString, Description: "certificate file to serve using TLS"},
+		{Name: "key_file", Type: TypeString, Description: "key file to serve using TLS"},
+		{Name: "drain_delay", Type: TypeDuration, Description: "time to fail healthchecks before closing the listener on shutdown"},
+	}
+	grpcFields = []Field{
+		{Name: "port", Type: TypeInt, Required: true},
+		{Name: "component", Type: TypeString, Description: "defaults to the app component"},
+		{Name: "periodic_memory", Type: TypeDuration, Description: "interval to return memory to the OS"},
+		{Name: "cert_file", Type: TypeString, Description: "certificate file to serve using TLS"},
+		{Name: "key_file", Type: TypeString, Description: "key file to serve using TLS"},
+	}
+)
+
+const (
+	// KindRedis redis datasource type
+	KindRedis = "redis"
+	// KindStatsd statsd metrics datasource type
+	KindStatsd = "statsd"
+	// KindElasticSearch elastic search datasource type
+	KindElasticSearch = "elasticsearch"
+	// KindHTTP http server type
+	KindHTTP = "http"
+	// KindGRPC gRPC server type
+	KindGRPC = "grpc"
+)
+
+// SQLDatasource datasource for mysql and postgres databases
+type SQLDatasource struct {
+	*goqu.Database
+	engine db.Engine
+}
+
+// Kind returns the database engine
+func (s *SQLDatasource) Kind() string {
+	return s.engine.String()
+}
+
+// Diagnose start diagnose check
+func (s *SQLDatasource) Diagnose() diagnose.ComponentReport {
+	return db.NewChecker(s.Database).Diagnose()
+}
+
+// Close closes the database connection pool
+func (s *SQLDatasource) Close() error {
+	return s.Db.Close()
+}
+
+// RedisDatasource datasource for redis
+type RedisDatasource struct {
+	*cache.RedisCache
+}
+
+// Kind returns redis type
+func (*RedisDatasource) Kind() string {
+	return KindRedis
+}
+
+// MetricsDatasource datasource for statsd metrics
+// the sample rate of every call is scaled by the configured sample_rate
+type MetricsDatasource struct {
+	*metrics.SampledClient
+}
+
+// Kind returns statsd type
+func (*MetricsDatasource) Kind() string {
+	return KindStatsd
+}
+
+// ElasticSearchDatasource datasource for elastic search
+type ElasticSearchDatasource struct {
+	*elasticsearch.ElasticSearch3Client
+}
+
+// Kind returns elasticsearch type
+func (*ElasticSearchDatasource) Kind() string {
+	return KindElasticSearch
+}
+
+// Close stops the elastic search client background processes
+func (es *ElasticSearchDatasource) Close() error {
+	es.Client.Stop()
+	return nil
+}
+
+// HTTPServer server for http
+type HTTPServer struct {
+	*http.Server
+	// metrics name of the statsd datasource used to count panics
+	metrics string
+}
+
+// Kind returns http type
+func (*HTTPServer) Kind() string {
+	return KindHTTP
+}
+
+// GRPCServer server for gRPC
+type GRPCServer struct {
+	*grpc.Server
+}
+
+// Kind returns grpc type
+func (*GRPCServer) Kind() string {
+	return KindGRPC
+}
+
+// Shutdown gracefully stops the server waiting for pending RPCs
+func (g *GRPCServer) Shutdown(ctx context.Context) error {
+	return g.GracefulStop(ctx)
+}
+
+func newSQLFactory(engine db.Engine) DatasourceFactory {
+	return func(name string, config *viper.Viper) (Datasource, error) {
+		opts := db.DatabaseConnectionOpts{
+			Host:               config.GetString("host"),
+			Database:           config.GetString("database"),
+			User:               config.GetString("user"),
+			Password:           config.GetString("password"),
+			Port:               config.GetString("port"),
+			Timeout:            config.GetInt("timeout"),
+			MaxConnections:     config.GetInt("max_connections"),
+			MaxIdleConnections: config.GetInt("max_idle_connections"),
+			ConnMaxLifetime:    config.GetInt("conn_max_lifetime"),
+			Params:             config.GetStringMapString("params"),
+		}
+		database, err := db.New(engine, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &SQLDatasource{Database: database, engine: engine}, nil
+	}
+}
+
+func newRedis(name string, config *viper.Viper) (Datasource, error) {
+	var writer cache.RedisOpts
+	if writerConfig := config.Sub("writer"); writerConfig != nil {
+		writer = getRedisOpts(writerConfig)
+	}
+	client, err := cache.NewRedis(getRedisOpts(config), writer)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisDatasource{RedisCache: client}, nil
+}
+
+func getRedisOpts(config *viper.Viper) cache.RedisOpts {
+	return cache.RedisOpts{
+		Host:     config.GetString("host"),
+		Port:     config.GetInt("port"),
+		DB:       config.GetInt("db"),
+		Password: config.GetString("password"),
+	}
+}
+
+func newStatsd(name string, config *viper.Viper) (Datasource, error) {
+	config.SetDefault("enabled", true)
+	config.SetDefault("buffer_size", 100)
+	config.SetDefault("sample_rate", 1)
+	client, err := metrics.New(
+		config.GetString("host"),
+		config.GetInt("port"),
+		config.GetBool("enabled"),
+		config.GetInt("buffer_size"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &MetricsDatasource{SampledClient: metrics.NewSampledClient(client, config.GetFloat64("sample_rate"))}, nil
+}
+
+func newElasticSearch(name string, config *viper.Viper) (Datasource, error) {
+	client, err := elasticsearch.NewElasticSearch3Client(elasticsearch.ElasticConfig{
+		Endpoint:           config.GetString("endpoint"),
+		Username:           config.GetString("username"),
+		Password:           config.GetString("password"),
+		Retries:            config.GetInt("retries"),
+		HealthCheckTimeout: config.GetDuration("health_check_timeout"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ElasticSearchDatasource{ElasticSearch3Client: client}, nil
+}
+
+func newHTTPServer(name string, config *viper.Viper) (Server, error) {
+	logger := log.NewLogger("bergamot.http." + name)
+	trusted, err := utils.ParseTrustedProxies(config.GetStringSlice("trusted_proxies"))
+	if err != nil {
+		return nil, err
+	}
+	httpConfig := http.Config{
+		Host:              config.GetString("host"),
+		Port:              config.GetString("port"),
+		Component:         config.GetString("component"),
+		AddLog:            config.GetBool("add_log"),
+		AddRequestID:      config.GetBool("add_request_id"),
+		AddRecovery:       config.GetBool("add_recovery"),
+		AddHealthCheck:    config.GetBool("add_health_check"),
+		AddOpenAPI:        config.GetBool("add_openapi"),
+		MaxReadBufferSize: config.GetInt("max_read_buffer_size"),
+		Gzip:              config.GetBool("gzip"),
+		GzipMinSize:       config.GetInt("gzip_min_size"),
+		AllowedOrigins:    config.GetStringSlice("allowed_origins"),
+		CertFile:          config.GetString("cert_file"),
+		KeyFile:           config.GetString("key_file"),
+		DrainDelay:        config.GetDuration("drain_delay"),
+		TrustedProxies:    trusted,
+	}
+	if accessLog := config.Sub("access_log"); accessLog != nil {
+		httpConfig.AddLog = true
+		httpConfig.LogFunc = http.NewAccessLog(http.AccessLogConfig{
+			Format:            http.AccessLogFormat(accessLog.GetString("format")),
+			Fields:            accessLog.GetStringSlice("fields"),
+			ExcludePaths:      accessLog.GetStringSlice("exclude_paths"),
+			SuccessSampleRate: accessLog.GetFloat64("success_sample_rate"),
+		}, logger).Serve
+	} else if httpConfig.AddLog {
+		httpConfig.LogFunc = http.NewStLogFunc(logger)
+	}
+	server := http.NewServer(httpConfig, logger).Init()
+	var authenticator auth.Authenticator
+	if authConfig := config.Sub("auth"); authConfig != nil {
+		if authenticator, err = getAuthenticator(authConfig); err != nil {
+			return nil, err
+		}
+		server.SetAuthenticator(authenticator)
+	}
+	if rateLimit := config.Sub("rate_limit"); rateLimit != nil {
+		if proxies := rateLimit.GetStringSlice("trusted_proxies"); len(proxies) > 0 {
+			if trusted, err = utils.ParseTrustedProxies(proxies); err != nil {
+				return nil, err
+			}
+		}
+		server.AddMiddleware(http.NewRateLimitMiddleware(http.RateLimitConfig{
+			Limiter:  ratelimit.NewMemoryLimiter(rateLimit.GetInt("limit"), rateLimit.GetDuration("window")),
+			KeyFunc:  getRateLimitKeyFunc(rateLimit.GetString("key"), trusted, authenticator),
+			PerRoute: rateLimit.GetBool("per_route"),
+		}, logger))
+	}
+	if value := config.Get("policies"); value != nil {
+		policies, err := auth.ParsePolicies(value)
+		if err != nil {
+			return nil, err
+		}
+		server.SetPolicies(policies)
+	}
+	return &HTTPServer{Server: server, metrics: config.GetString("metrics")}, nil
+}
+
+// setupMetrics sets the statsd datasource selected by the metrics setting of http servers
+func (a *App) setupMetrics() error {
+	var errs ValidationErrors
+	for _, name := range a.getServerNames() {
+		server, ok := a.Servers[name].(*HTTPServer)
+		if !ok || server.metrics == "" {
+			continue
+		}
+		client, ok := a.Datasources[server.metrics].(*MetricsDatasource)
+		if !ok {
+			errs = append(errs, NewValidationError("servers."+name+".metrics", "unknown statsd datasource %q", server.metrics))
+			continue
+		}
+		server.SetMetricsClient(client)
+	}
+	return errs.Err()
+}
+
+// getRateLimitKeyFunc returns the key function for ip, user or header:<name>
+// client IPs are read from forwarding headers only for trusted proxies,
+// users are authenticated using the authenticator of the server
+func getRateLimitKeyFunc(key string, trusted []*net.IPNet, authenticator auth.Authenticator) http.RateLimitKeyFunc {
+	byIP := http.KeyByTrustedIP(trusted)
+	switch {
+	case key == "user":
+		return http.KeyByAuthenticatedUser(authenticator, byIP)
+	case strings.HasPrefix(key, "header:"):
+		return http.KeyByHeaderOr(strings.TrimPrefix(key, "header:"), byIP)
+	}
+	return byIP
+}
+
+// getAuthenticator builds a chain with all the configured authenticators
+func getAuthenticator(config *viper.Viper) (auth.Authenticator, error) {
+	var chain auth.Chain
+	roles := config.GetStringMapStringSlice("roles")
+	permissions := config.GetStringMapStringSlice("permissions")
+	if tokens := config.GetStringMapString("tokens"); len(tokens) > 0 {
+		users := make(map[string]*auth.User, len(tokens))
+		for name, token := range tokens {
+			users[token] = &auth.User{Name: name, Roles: roles[name], Permissions: permissions[name]}
+		}
+		chain = append(chain, auth.NewBearerAuthenticator(users))
+	}
+	if passwords := config.GetStringMapString("basic_users"); len(passwords) > 0 {
+		users := make(map[string]auth.BasicUser, len(passwords))
+		for name, password := range passwords {
+			users[name] = auth.BasicUser{Password: password, Roles: roles[name], Permissions: permissions[name]}
+		}
+		chain = append(chain, auth.NewBasicAuthenticator(users))
+	}
+	if config.GetString("jwt_secret") != "" || config.GetString("jwks_file") != "" {
+		jwt, err := auth.NewJWTAuthenticator(auth.JWTConfig{
+			Secret:   []byte(config.GetString("jwt_secret")),
+			JWKSFile: config.GetString("jwks_file"),
+			Issuer:   config.GetString("jwt_issuer"),
+			Audience: config.GetString("jwt_audience"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, jwt)
+	}
+	return chain, nil
+}
+
+func newGRPCServer(name string, config *viper.Viper) (Server, error) {
+	server := grpc.New(grpc.Config{
+		Port:           config.GetString("port"),
+		Component:      config.GetString("component"),
+		PeriodicMemory: config.GetDuration("periodic_memory"),
+		CertFile:       config.GetString("cert_file"),
+		KeyFile:        config.GetString("key_file"),
+	})
+	server.SetLogger(log.NewLogger("bergamot.grpc." + name))
+	return &GRPCServer{Server: server}, nil
+}
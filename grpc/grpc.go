@@ -1,11 +1,15 @@
 package grpc
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"net"
 	"runtime/debug"
+	"sync"
 	"time"
 
+	"github.com/alauda/bergamot/diagnose"
 	"github.com/alauda/bergamot/log"
 
 	"google.golang.org/grpc"
@@ -22,9 +26,21 @@ type Registration func(server *grpc.Server)
 
 // Server is a multiplexed server that adds a default HTTP1.1 healthcheck
 type Server struct {
-	config     Config
-	registrars []Registration
-	log        log.StandardLogger
+	config             Config
+	registrars         []Registration
+	httpHandlers       map[string]http.Handler
+	options            []grpc.ServerOption
+	unaryInterceptors  []grpc.UnaryServerInterceptor
+	streamInterceptors []grpc.StreamServerInterceptor
+	checker            *diagnose.HealthChecker
+	log                log.StandardLogger
+
+	lock       sync.Mutex
+	stopped    bool
+	listener   net.Listener
+	grpcServer *grpc.Server
+	httpServer *http.Server
+	done       chan struct{}
 }
 
 // Config configuration for GRPC server
@@ -32,13 +48,32 @@ type Config struct {
 	Port           string
 	Component      string
 	PeriodicMemory time.Duration
+	// CertFile and KeyFile will serve both gRPC and the
+	// HTTP1.1 healthcheck using TLS when set
+	CertFile string
+	KeyFile  string
+}
+
+// IsTLS returns true if both certificate and key files are set
+func (c Config) IsTLS() bool {
+	return c.CertFile != "" && c.KeyFile != ""
 }
 
 // New constructor function for the gRPC server
 func New(config Config) *Server {
 	return &Server{
-		config:     config,
-		registrars: make([]Registration, 0, 1),
+		config:       config,
+		registrars:   make([]Registration, 0, 1),
+		httpHandlers: map[string]http.Handler{},
+		log:          log.NewLogger("bergamot.grpc"),
+		done:         make(chan struct{}),
+	}
+}
+
+// SetLogger sets the logger used to report serving errors
+func (g *Server) SetLogger(logger log.StandardLogger) {
+	if logger != nil {
+		g.log = logger
 	}
 }
 
@@ -49,13 +84,44 @@ func (g *Server) Add(registration Registration) {
 	g.registrars = append(g.registrars, registration)
 }
 
+// AddOption adds options used to create the grpc.Server
+// should be executed before the Start method
+func (g *Server) AddOption(opts ...grpc.ServerOption) {
+	g.options = append(g.options, opts...)
+}
+
+// AddUnaryInterceptor adds unary interceptors executed in the order they were added
+// should be executed before the Start method
+func (g *Server) AddUnaryInterceptor(interceptors ...grpc.UnaryServerInterceptor) {
+	g.unaryInterceptors = append(g.unaryInterceptors, interceptors...)
+}
+
+// AddStreamInterceptor adds stream interceptors executed in the order they were added
+// should be executed before the Start method
+func (g *Server) AddStreamInterceptor(interceptors ...grpc.StreamServerInterceptor) {
+	g.streamInterceptors = append(g.streamInterceptors, interceptors...)
+}
+
+// SetHealthChecker registers the standard grpc.health.v1.Health service
+// backed by the checker and serves its report on the HTTP1.1 healthcheck server.
+// should be executed before the Start method
+func (g *Server) SetHealthChecker(checker *diagnose.HealthChecker) {
+	g.checker = checker
+}
+
+// HandleHTTP adds a handler to the HTTP1.1 healthcheck server for the given pattern
+// should be executed before the Start method
+func (g *Server) HandleHTTP(pattern string, handler http.Handler) {
+	g.httpHandlers[pattern] = handler
+}
+
 // Start will start serving on the GRPC server and block further execution
-// should prefebly run inside a goroutine
+// should prefebly run inside a goroutine. Returns nil once stopped
 func (g *Server) Start() error {
 	if len(g.registrars) == 0 {
 		return errors.New("No registration method added. impossible to boot")
 	}
-	listen, err := net.Listen("tcp", ":"+g.config.Port)
+	listen, err := g.listen()
 	if err != nil {
 		return err
 	}
@@ -73,39 +139,170 @@ func (g *Server) Start() error {
 	httpListener := mux.Match(cmux.Any())
 
 	// initiating grpc server
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(g.getOptions()...)
 	// registering handlers
 	for _, r := range g.registrars {
 		r(grpcServer)
 	}
+	if g.checker != nil {
+		RegisterHealthServer(grpcServer, NewHealthServer(g.checker))
+	}
 	reflection.Register(grpcServer)
 
 	// creating http server
 	httpServer := http.NewServeMux()
-	httpServer.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintf(w, "OK")
-	})
+	if g.checker != nil {
+		httpServer.Handle("/", healthHandler(g.checker))
+	} else {
+		httpServer.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "OK")
+		})
+	}
+	for pattern, handler := range g.httpHandlers {
+		httpServer.Handle(pattern, handler)
+	}
 
 	httpS := &http.Server{
 		Handler: httpServer,
 	}
 
+	g.lock.Lock()
+	if g.stopped {
+		g.lock.Unlock()
+		listen.Close()
+		return nil
+	}
+	g.listener = listen
+	g.grpcServer = grpcServer
+	g.httpServer = httpS
+	g.lock.Unlock()
+
 	// starting it all
-	go grpcServer.Serve(grpcListener)
-	go httpS.Serve(httpListener)
+	go func() {
+		if err := grpcServer.Serve(grpcListener); err != nil && !g.isStopped() {
+			g.log.Errorf("grpc server on port %s stopped: %v", g.config.Port, err)
+		}
+	}()
+	go func() {
+		if err := httpS.Serve(httpListener); err != nil && err != http.ErrServerClosed && !g.isStopped() {
+			g.log.Errorf("http healthcheck server on port %s stopped: %v", g.config.Port, err)
+		}
+	}()
 	// will periodically free memory if set
 	if g.config.PeriodicMemory > 0 {
 		go g.PeriodicFree(g.config.PeriodicMemory)
 	}
 
 	// Start serving...
-	return mux.Serve()
+	if err := mux.Serve(); err != nil && !g.isStopped() {
+		g.log.Errorf("grpc listener on port %s stopped: %v", g.config.Port, err)
+		return err
+	}
+	return nil
+}
+
+// listen opens the tcp listener, wrapped with TLS if configured
+func (g *Server) listen() (net.Listener, error) {
+	var (
+		cert tls.Certificate
+		err  error
+	)
+	if g.config.IsTLS() {
+		if cert, err = tls.LoadX509KeyPair(g.config.CertFile, g.config.KeyFile); err != nil {
+			return nil, err
+		}
+	}
+	listen, err := net.Listen("tcp", ":"+g.config.Port)
+	if err != nil || !g.config.IsTLS() {
+		return listen, err
+	}
+	return tls.NewListener(listen, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+	}), nil
+}
+
+// getOptions returns the server options adding the chained interceptors
+func (g *Server) getOptions() []grpc.ServerOption {
+	opts := make([]grpc.ServerOption, 0, len(g.options)+2)
+	opts = append(opts, g.options...)
+	if len(g.unaryInterceptors) > 0 {
+		opts = append(opts, grpc.UnaryInterceptor(ChainUnaryInterceptors(g.unaryInterceptors...)))
+	}
+	if len(g.streamInterceptors) > 0 {
+		opts = append(opts, grpc.StreamInterceptor(ChainStreamInterceptors(g.streamInterceptors...)))
+	}
+	return opts
+}
+
+func (g *Server) isStopped() bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.stopped
+}
+
+// stop marks the server as stopped and returns the running servers
+func (g *Server) stop() (*grpc.Server, *http.Server, net.Listener) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if !g.stopped {
+		g.stopped = true
+		close(g.done)
+	}
+	return g.grpcServer, g.httpServer, g.listener
+}
+
+// GracefulStop stops accepting connections and waits for pending RPCs
+// and healthcheck requests to finish. If the context is done first
+// all connections are closed and the context error is returned
+func (g *Server) GracefulStop(ctx context.Context) error {
+	grpcServer, httpServer, listener := g.stop()
+	if grpcServer == nil {
+		return nil
+	}
+	// the multiplexed listeners share the root listener that is closed
+	// by the first server stopped, the healthcheck server goes first
+	// because its Shutdown reports the error of closing it again
+	err := httpServer.Shutdown(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
+	listener.Close()
+	if err == nil {
+		err = ctx.Err()
+	}
+	return err
+}
+
+// Stop closes the listener and all open connections immediately
+func (g *Server) Stop() {
+	grpcServer, httpServer, listener := g.stop()
+	if grpcServer == nil {
+		return
+	}
+	grpcServer.Stop()
+	httpServer.Close()
+	listener.Close()
 }
 
 // PeriodicFree returns memory to OS given a span of time
+// until the server is stopped
 func (g *Server) PeriodicFree(d time.Duration) {
-	tick := time.Tick(d)
-	for _ = range tick {
-		debug.FreeOSMemory()
+	tick := time.NewTicker(d)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			debug.FreeOSMemory()
+		case <-g.done:
+			return
+		}
 	}
 }
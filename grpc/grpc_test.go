@@ -0,0 +1,210 @@
+package grpc_test
+
+import (
+	"context"
+	goerrors "errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alauda/bergamot/contexts"
+	"github.com/alauda/bergamot/diagnose"
+	"github.com/alauda/bergamot/errors"
+	bgrpc "github.com/alauda/bergamot/grpc"
+	"github.com/alauda/bergamot/log"
+	"github.com/alauda/bergamot/loggo"
+	"github.com/alauda/bergamot/metrics"
+	"github.com/alauda/bergamot/middleware"
+
+	netcontext "golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestChainUnaryInterceptors(t *testing.T) {
+	var calls []string
+	interceptor := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx netcontext.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			calls = append(calls, name)
+			return handler(ctx, req)
+		}
+	}
+	chained := bgrpc.ChainUnaryInterceptors(interceptor("first"), interceptor("second"))
+	res, err := chained(context.Background(), "req", &grpc.UnaryServerInfo{}, func(ctx netcontext.Context, req interface{}) (interface{}, error) {
+		calls = append(calls, "handler")
+		return req, nil
+	})
+	if err != nil || res != "req" {
+		t.Errorf("unexpected result %v, %v", res, err)
+	}
+	if len(calls) != 3 || calls[0] != "first" || calls[1] != "second" || calls[2] != "handler" {
+		t.Errorf("unexpected call order: %v", calls)
+	}
+}
+
+func TestServerGracefulStop(t *testing.T) {
+	port := getFreePort(t)
+	server := bgrpc.New(bgrpc.Config{Port: port, PeriodicMemory: time.Second})
+	server.Add(func(*grpc.Server) {})
+	errs := make(chan error, 1)
+	go func() { errs <- server.Start() }()
+	waitListening(t, "127.0.0.1:"+port)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := server.GracefulStop(ctx); err != nil {
+		t.Errorf("unexpected error stopping: %v", err)
+	}
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Errorf("expected Start to return nil after stop, got: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Start did not return after stop")
+	}
+}
+
+// getFreePort returns a port that was free when checked
+func getFreePort(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	return port
+}
+
+// waitListening dials the address until it accepts connections
+func waitListening(t *testing.T, addr string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server not listening on %s: %v", addr, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+type fakeComponent struct {
+	name string
+	err  error
+}
+
+func (f fakeComponent) Diagnose() diagnose.ComponentReport {
+	return diagnose.SimpleDiagnose(f.name, func() error { return f.err })
+}
+
+func TestHealthServerCheck(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Service  string
+		Expected bgrpc.ServingStatus
+		Code     codes.Code
+	}
+
+	checker, _ := diagnose.New()
+	checker.Add(fakeComponent{name: "mysql"}).Add(fakeComponent{name: "redis", err: goerrors.New("timeout")})
+	server := bgrpc.NewHealthServer(checker)
+
+	table := []TestCase{
+		{"overall", "", bgrpc.StatusNotServing, codes.OK},
+		{"healthy component", "mysql", bgrpc.StatusServing, codes.OK},
+		{"failing component", "redis", bgrpc.StatusNotServing, codes.OK},
+		{"unknown component", "kafka", bgrpc.StatusUnknown, codes.NotFound},
+	}
+
+	for i, test := range table {
+		res, err := server.Check(context.Background(), &bgrpc.HealthCheckRequest{Service: test.Service})
+		if grpc.Code(err) != test.Code {
+			t.Errorf("%d - %s -- expected code %v got %v", i, test.Name, test.Code, grpc.Code(err))
+			continue
+		}
+		if err == nil && res.Status != test.Expected {
+			t.Errorf("%d - %s -- expected status %v got %v", i, test.Name, test.Expected, res.Status)
+		}
+	}
+}
+
+// codeLogger records the code of the logged requests
+type codeLogger struct {
+	log.EmptyLogger
+	codes []string
+}
+
+func (l *codeLogger) StInfo(message string, fields loggo.Fields) {
+	l.StError(message, fields)
+}
+
+func (l *codeLogger) StError(message string, fields loggo.Fields) {
+	if message == "request" {
+		l.codes = append(l.codes, fields["code"].(string))
+	}
+}
+
+func TestDefaultUnaryInterceptors(t *testing.T) {
+	type TestCase struct {
+		Name      string
+		RequestID string
+		Handler   grpc.UnaryHandler
+		Code      codes.Code
+	}
+
+	var requestID string
+	table := []TestCase{
+		{
+			"request id from metadata",
+			"abc",
+			func(ctx netcontext.Context, req interface{}) (interface{}, error) {
+				requestID = contexts.GetRequestID(ctx)
+				return req, nil
+			},
+			codes.OK,
+		},
+		{
+			"alauda error",
+			"abc",
+			func(ctx netcontext.Context, req interface{}) (interface{}, error) {
+				return nil, errors.New("test", errors.ErrorCodePermissionDenied)
+			},
+			codes.PermissionDenied,
+		},
+		{
+			"panic",
+			"abc",
+			func(ctx netcontext.Context, req interface{}) (interface{}, error) {
+				panic("boom")
+			},
+			codes.Internal,
+		},
+	}
+
+	logger := &codeLogger{}
+	interceptors := bgrpc.DefaultUnaryInterceptors(logger, middleware.NewMetrics("test", 1, metrics.ClosedClient{}))
+	chained := bgrpc.ChainUnaryInterceptors(interceptors...)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	for i, test := range table {
+		ctx := metadata.NewContext(context.Background(), metadata.Pairs(bgrpc.RequestIDKey, test.RequestID))
+		_, err := chained(ctx, "req", info, test.Handler)
+		if grpc.Code(err) != test.Code {
+			t.Errorf("%d - %s -- expected code %v got %v", i, test.Name, test.Code, grpc.Code(err))
+		}
+		if strings.Contains(grpc.ErrorDesc(err), "boom") {
+			t.Errorf("%d - %s -- expected panic value to be hidden got %q", i, test.Name, grpc.ErrorDesc(err))
+		}
+		if len(logger.codes) != i+1 || logger.codes[i] != test.Code.String() {
+			t.Errorf("%d - %s -- expected logged code %v got %v", i, test.Name, test.Code, logger.codes)
+		}
+	}
+	if requestID != "abc" {
+		t.Errorf("expected request id abc got %q", requestID)
+	}
+}
@@ -0,0 +1,157 @@
+package grpc
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/alauda/bergamot/diagnose"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// The messages and service description below follow the standard
+// grpc.health.v1 protocol https://github.com/grpc/grpc/blob/master/doc/health-checking.md
+// written by hand because the vendored gRPC version does not include it
+
+// HealthCheckRequest request message of the grpc.health.v1.Health service
+type HealthCheckRequest struct {
+	Service string `protobuf:"bytes,1,opt,name=service" json:"service,omitempty"`
+}
+
+// Reset resets the message
+func (m *HealthCheckRequest) Reset() { *m = HealthCheckRequest{} }
+
+// String returns the message in text format
+func (m *HealthCheckRequest) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage marks the struct as a protobuf message
+func (*HealthCheckRequest) ProtoMessage() {}
+
+// ServingStatus status of a service in a HealthCheckResponse
+type ServingStatus int32
+
+const (
+	// StatusUnknown the status could not be determined
+	StatusUnknown ServingStatus = 0
+	// StatusServing the service is healthy
+	StatusServing ServingStatus = 1
+	// StatusNotServing the service or one of its dependencies is failing
+	StatusNotServing ServingStatus = 2
+)
+
+var servingStatusNames = map[ServingStatus]string{
+	StatusUnknown:    "UNKNOWN",
+	StatusServing:    "SERVING",
+	StatusNotServing: "NOT_SERVING",
+}
+
+// String returns the protocol name of the status
+func (s ServingStatus) String() string {
+	return servingStatusNames[s]
+}
+
+// HealthCheckResponse response message of the grpc.health.v1.Health service
+type HealthCheckResponse struct {
+	Status ServingStatus `protobuf:"varint,1,opt,name=status,enum=grpc.health.v1.HealthCheckResponse_ServingStatus" json:"status,omitempty"`
+}
+
+// Reset resets the message
+func (m *HealthCheckResponse) Reset() { *m = HealthCheckResponse{} }
+
+// String returns the message in text format
+func (m *HealthCheckResponse) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage marks the struct as a protobuf message
+func (*HealthCheckResponse) ProtoMessage() {}
+
+// HealthServer server API of the grpc.health.v1.Health service
+type HealthServer interface {
+	Check(context.Context, *HealthCheckRequest) (*HealthCheckResponse, error)
+}
+
+// RegisterHealthServer registers the health service in a grpc.Server
+func RegisterHealthServer(s *grpc.Server, srv HealthServer) {
+	s.RegisterService(&healthServiceDesc, srv)
+}
+
+func healthCheckHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HealthCheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HealthServer).Check(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpc.health.v1.Health/Check",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HealthServer).Check(ctx, req.(*HealthCheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var healthServiceDesc = grpc.ServiceDesc{
+	ServiceName: "grpc.health.v1.Health",
+	HandlerType: (*HealthServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Check",
+			Handler:    healthCheckHandler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "grpc/health/v1/health.proto",
+}
+
+// NewHealthServer constructor for a HealthServer backed by a HealthChecker
+// the empty service returns the overall status and each component name
+// can be used as a service to check its own status
+func NewHealthServer(checker *diagnose.HealthChecker) HealthServer {
+	return &healthServer{checker: checker}
+}
+
+type healthServer struct {
+	checker *diagnose.HealthChecker
+}
+
+// Check runs the health check and returns the status of the requested service
+func (h *healthServer) Check(ctx context.Context, req *HealthCheckRequest) (*HealthCheckResponse, error) {
+	report := h.checker.Check()
+	if req.Service == "" {
+		return &HealthCheckResponse{Status: getServingStatus(report.Status)}, nil
+	}
+	for _, component := range report.Details {
+		if component.Name == req.Service {
+			return &HealthCheckResponse{Status: getServingStatus(component.Status)}, nil
+		}
+	}
+	return nil, grpc.Errorf(codes.NotFound, "unknown service %s", req.Service)
+}
+
+func getServingStatus(status diagnose.HealthStatus) ServingStatus {
+	switch status {
+	case diagnose.StatusOK:
+		return StatusServing
+	case diagnose.StatusError:
+		return StatusNotServing
+	}
+	return StatusUnknown
+}
+
+// healthHandler serves the health report on the HTTP1.1 healthcheck server
+// returning 503 when any component is failing
+func healthHandler(checker *diagnose.HealthChecker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := checker.Check()
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		if report.Status != diagnose.StatusOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	}
+}
@@ -0,0 +1,200 @@
+package grpc
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/alauda/bergamot/contexts"
+	"github.com/alauda/bergamot/errors"
+	"github.com/alauda/bergamot/log"
+	"github.com/alauda/bergamot/loggo"
+	"github.com/alauda/bergamot/middleware"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDKey metadata key used to propagate the request ID
+const RequestIDKey = "x-request-id"
+
+// DefaultUnaryInterceptors returns the request ID, error, log, metrics and recovery
+// interceptors in the order they should be added to the server
+func DefaultUnaryInterceptors(logger log.StLogger, metrics middleware.BaseMetrics) []grpc.UnaryServerInterceptor {
+	return []grpc.UnaryServerInterceptor{
+		RequestIDInterceptor,
+		ErrorInterceptor,
+		NewLogInterceptor(logger),
+		NewMetricsInterceptor(metrics),
+		NewRecoveryInterceptor(logger),
+	}
+}
+
+// DefaultStreamInterceptors returns the stream version of DefaultUnaryInterceptors
+func DefaultStreamInterceptors(logger log.StLogger, metrics middleware.BaseMetrics) []grpc.StreamServerInterceptor {
+	return []grpc.StreamServerInterceptor{
+		RequestIDStreamInterceptor,
+		ErrorStreamInterceptor,
+		NewLogStreamInterceptor(logger),
+		NewMetricsStreamInterceptor(metrics),
+		NewRecoveryStreamInterceptor(logger),
+	}
+}
+
+// RequestIDInterceptor reads the request ID from the incoming metadata
+// or generates a new one and sets it in the context
+func RequestIDInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(setRequestID(ctx), req)
+}
+
+// RequestIDStreamInterceptor stream version of RequestIDInterceptor
+func RequestIDStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &serverStream{ServerStream: stream, ctx: setRequestID(stream.Context())})
+}
+
+func setRequestID(ctx context.Context) context.Context {
+	var requestID string
+	if md, ok := metadata.FromContext(ctx); ok {
+		if values := md[RequestIDKey]; len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if !contexts.IsValidRequestID(requestID) {
+		requestID = contexts.NewRequestID()
+	}
+	return contexts.SetRequestID(ctx, requestID)
+}
+
+// ErrorInterceptor translates errors.AlaudaError returned by handlers
+// into gRPC errors using errors.ToGRPCStatus
+func ErrorInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	res, err := handler(ctx, req)
+	return res, errors.ToGRPCStatus(err)
+}
+
+// ErrorStreamInterceptor stream version of ErrorInterceptor
+func ErrorStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return errors.ToGRPCStatus(handler(srv, stream))
+}
+
+// NewLogInterceptor returns an interceptor that logs every call
+// using the error level for failed calls
+func NewLogInterceptor(logger log.StLogger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		begin := time.Now()
+		res, err := handler(ctx, req)
+		logCall(ctx, logger, info.FullMethod, begin, err)
+		return res, err
+	}
+}
+
+// NewLogStreamInterceptor stream version of NewLogInterceptor
+func NewLogStreamInterceptor(logger log.StLogger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		begin := time.Now()
+		err := handler(srv, stream)
+		logCall(stream.Context(), logger, info.FullMethod, begin, err)
+		return err
+	}
+}
+
+// logCall logs a finished call, the code is derived using errors.ToGRPCStatus
+// because the error interceptor runs outside the log interceptor
+func logCall(ctx context.Context, logger log.StLogger, fullMethod string, begin time.Time, err error) {
+	service, method := splitMethod(fullMethod)
+	fields := log.AddRequestID(ctx, loggo.Fields{
+		"service": service,
+		"method":  method,
+		"code":    grpc.Code(errors.ToGRPCStatus(err)).String(),
+		"latency": time.Since(begin).String(),
+	})
+	if err != nil {
+		fields["error"] = err.Error()
+		logger.StError("request", fields)
+		return
+	}
+	logger.StInfo("request", fields)
+}
+
+// NewMetricsInterceptor returns an interceptor that generates latency and status
+// metrics for every call using the service as module and the method as action
+func NewMetricsInterceptor(metrics middleware.BaseMetrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		begin := time.Now()
+		res, err := handler(ctx, req)
+		service, method := splitMethod(info.FullMethod)
+		metrics.GenerateMetrics(begin, service, "grpc", method, err)
+		return res, err
+	}
+}
+
+// NewMetricsStreamInterceptor stream version of NewMetricsInterceptor
+func NewMetricsStreamInterceptor(metrics middleware.BaseMetrics) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		begin := time.Now()
+		err := handler(srv, stream)
+		service, method := splitMethod(info.FullMethod)
+		metrics.GenerateMetrics(begin, service, "grpc", method, err)
+		return err
+	}
+}
+
+// NewRecoveryInterceptor returns an interceptor that recovers from panics
+// in handlers returning a codes.Internal error instead
+func NewRecoveryInterceptor(logger log.StLogger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverCall(ctx, logger, info.FullMethod, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// NewRecoveryStreamInterceptor stream version of NewRecoveryInterceptor
+func NewRecoveryStreamInterceptor(logger log.StLogger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverCall(stream.Context(), logger, info.FullMethod, r)
+			}
+		}()
+		return handler(srv, stream)
+	}
+}
+
+// recoverCall logs a recovered panic with its stack and returns
+// a generic internal error so the panic value does not reach clients
+func recoverCall(ctx context.Context, logger log.StLogger, fullMethod string, r interface{}) error {
+	logger.StError("panic", log.AddRequestID(ctx, loggo.Fields{
+		"method": fullMethod,
+		"panic":  fmt.Sprint(r),
+		"stack":  string(debug.Stack()),
+	}))
+	return grpc.Errorf(codes.Internal, "%s", errors.ErrorMessageList[errors.ErrorCodeUnknownIssue].Message)
+}
+
+// splitMethod splits a full method like /package.Service/Method
+// into service and method names
+func splitMethod(fullMethod string) (service, method string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
+
+// serverStream ServerStream with a custom context
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the stream context
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,131 @@
+package grpc
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/alauda/bergamot/contexts"
+	"github.com/alauda/bergamot/errors"
+	"github.com/alauda/bergamot/log"
+	"github.com/alauda/bergamot/ratelimit"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// RateLimitKeyFunc returns the key used to limit a call
+// calls with an empty key are not limited
+type RateLimitKeyFunc func(ctx context.Context, fullMethod string) string
+
+// KeyByPeer limits calls by the client IP
+func KeyByPeer(ctx context.Context, fullMethod string) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return "ip:" + addr
+}
+
+// KeyByUser limits calls by the user stored using contexts.SetUser
+// anonymous calls are limited by client IP
+func KeyByUser(ctx context.Context, fullMethod string) string {
+	if user := ratelimit.UserKey(contexts.GetUser(ctx)); user != "" {
+		return "user:" + user
+	}
+	return KeyByPeer(ctx, fullMethod)
+}
+
+// KeyByMetadata limits calls by the value of a metadata key like x-api-key
+// calls without the key are limited by client IP
+// so omitting the key does not skip the limit
+func KeyByMetadata(name string) RateLimitKeyFunc {
+	name = strings.ToLower(name)
+	return func(ctx context.Context, fullMethod string) string {
+		md, ok := metadata.FromContext(ctx)
+		if !ok || len(md[name]) == 0 || md[name][0] == "" {
+			return KeyByPeer(ctx, fullMethod)
+		}
+		return "metadata:" + md[name][0]
+	}
+}
+
+// RateLimitConfig configuration for the rate limit interceptors
+type RateLimitConfig struct {
+	Limiter ratelimit.Limiter
+	// KeyFunc defaults to KeyByPeer
+	KeyFunc RateLimitKeyFunc
+	// PerMethod limits each method separately instead of all methods together
+	PerMethod bool
+}
+
+// NewRateLimitInterceptor limits the number of calls by key
+// rejecting calls over the limit with a ResourceExhausted error.
+// Limiter errors are logged and the call is allowed
+func NewRateLimitInterceptor(config RateLimitConfig, logger log.Logger) grpc.UnaryServerInterceptor {
+	limit := newRateLimit(config, logger)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, err := limit(ctx, info.FullMethod)
+		if md != nil {
+			grpc.SetHeader(ctx, md)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// NewRateLimitStreamInterceptor stream version of NewRateLimitInterceptor
+func NewRateLimitStreamInterceptor(config RateLimitConfig, logger log.Logger) grpc.StreamServerInterceptor {
+	limit := newRateLimit(config, logger)
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		md, err := limit(stream.Context(), info.FullMethod)
+		if md != nil {
+			stream.SetHeader(md)
+		}
+		if err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// newRateLimit returns a function that counts a call
+// and returns the rate limit headers and the error if over the limit
+func newRateLimit(config RateLimitConfig, logger log.Logger) func(ctx context.Context, fullMethod string) (metadata.MD, error) {
+	if config.KeyFunc == nil {
+		config.KeyFunc = KeyByPeer
+	}
+	logger = log.GetSafe(logger)
+	return func(ctx context.Context, fullMethod string) (metadata.MD, error) {
+		key := config.KeyFunc(ctx, fullMethod)
+		if key == "" {
+			return nil, nil
+		}
+		if config.PerMethod {
+			key += ":" + fullMethod
+		}
+		result, err := config.Limiter.Allow(key)
+		if err != nil {
+			logger.Errorf("Rate limiter failed for %s: %v", key, err)
+			return nil, nil
+		}
+		md := metadata.Pairs(
+			"x-ratelimit-limit", strconv.Itoa(result.Limit),
+			"x-ratelimit-remaining", strconv.Itoa(result.Remaining),
+			"x-ratelimit-reset", strconv.FormatInt(ratelimit.Seconds(result.Reset), 10),
+		)
+		if result.Allowed {
+			return md, nil
+		}
+		md["retry-after"] = []string{strconv.FormatInt(ratelimit.Seconds(result.RetryAfter), 10)}
+		return md, errors.ToGRPCStatus(ratelimit.NewTooManyRequestsError(result))
+	}
+}
@@ -0,0 +1,188 @@
+package http
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/alauda/bergamot/log"
+	"github.com/alauda/bergamot/loggo"
+)
+
+// AccessLogFormat format used to print the access log
+type AccessLogFormat string
+
+const (
+	// AccessLogFormatFields structured log with the selected fields
+	AccessLogFormatFields AccessLogFormat = "fields"
+	// AccessLogFormatCommon NCSA common log format
+	AccessLogFormatCommon AccessLogFormat = "common"
+	// AccessLogFormatCombined NCSA combined log format
+	AccessLogFormatCombined AccessLogFormat = "combined"
+)
+
+// Access log fields
+const (
+	AccessLogMethod    = "method"
+	AccessLogPath      = "path"
+	AccessLogQuery     = "query"
+	AccessLogStatus    = "status"
+	AccessLogLatency   = "latency"
+	AccessLogBytes     = "bytes"
+	AccessLogClientIP  = "client_ip"
+	AccessLogUserAgent = "user_agent"
+	AccessLogReferer   = "referer"
+	AccessLogProtocol  = "protocol"
+	AccessLogRequestID = "request_id"
+)
+
+// AccessLogDefaultFields fields logged when none are selected
+var AccessLogDefaultFields = []string{
+	AccessLogMethod,
+	AccessLogPath,
+	AccessLogQuery,
+	AccessLogStatus,
+	AccessLogLatency,
+	AccessLogBytes,
+	AccessLogClientIP,
+	AccessLogUserAgent,
+	AccessLogRequestID,
+}
+
+// AccessLogConfig configuration for the access log
+type AccessLogConfig struct {
+	// Format defaults to AccessLogFormatFields
+	Format AccessLogFormat
+	// Fields selected fields for AccessLogFormatFields
+	// defaults to AccessLogDefaultFields
+	Fields []string
+	// ExcludePaths paths that will not be logged like /_ping
+	ExcludePaths []string
+	// SuccessSampleRate fraction of 2xx responses logged
+	// values outside (0,1) will log all responses
+	SuccessSampleRate float64
+}
+
+// AccessLog middleware that logs requests after the handler finished
+type AccessLog struct {
+	config  AccessLogConfig
+	logger  log.Logger
+	exclude map[string]struct{}
+}
+
+// NewAccessLog constructor function for AccessLog
+func NewAccessLog(config AccessLogConfig, logger log.Logger) *AccessLog {
+	if config.Format == "" {
+		config.Format = AccessLogFormatFields
+	}
+	if len(config.Fields) == 0 {
+		config.Fields = AccessLogDefaultFields
+	}
+	exclude := make(map[string]struct{}, len(config.ExcludePaths))
+	for _, path := range config.ExcludePaths {
+		exclude[path] = struct{}{}
+	}
+	return &AccessLog{
+		config:  config,
+		logger:  logger,
+		exclude: exclude,
+	}
+}
+
+// Serve calls the next handlers and logs the request
+func (a *AccessLog) Serve(ctx mux.Context) {
+	if _, ok := a.exclude[ctx.Path()]; ok {
+		ctx.Next()
+		return
+	}
+	start := time.Now()
+	ctx.Next()
+	latency := time.Since(start)
+
+	status := ctx.StatusCode()
+	if !a.shouldLog(status) {
+		return
+	}
+	switch a.config.Format {
+	case AccessLogFormatCommon, AccessLogFormatCombined:
+		a.logger.Infof("%s", a.getLine(ctx, start, status))
+	default:
+		fields := a.getFields(ctx, latency, status)
+		if status >= 500 {
+			a.logger.StError("request", fields)
+		} else {
+			a.logger.StInfo("request", fields)
+		}
+	}
+}
+
+// shouldLog applies the sampling for successful requests
+func (a *AccessLog) shouldLog(status int) bool {
+	rate := a.config.SuccessSampleRate
+	if status < 200 || status >= 300 || rate <= 0 || rate >= 1 {
+		return true
+	}
+	return rand.Float64() < rate
+}
+
+func (a *AccessLog) getFields(ctx mux.Context, latency time.Duration, status int) loggo.Fields {
+	fields := make(loggo.Fields, len(a.config.Fields))
+	for _, field := range a.config.Fields {
+		var value interface{}
+		switch field {
+		case AccessLogMethod:
+			value = ctx.Method()
+		case AccessLogPath:
+			value = ctx.Path()
+		case AccessLogQuery:
+			value = ctx.Request().URL.RawQuery
+		case AccessLogStatus:
+			value = status
+		case AccessLogLatency:
+			value = latency.String()
+		case AccessLogBytes:
+			value = GetResponseSize(ctx)
+		case AccessLogClientIP:
+			value = ctx.RemoteAddr()
+		case AccessLogUserAgent:
+			value = ctx.Request().UserAgent()
+		case AccessLogReferer:
+			value = ctx.Request().Referer()
+		case AccessLogProtocol:
+			value = ctx.Request().Proto
+		case AccessLogRequestID:
+			value = GetRequestID(ctx)
+		}
+		if value != nil && value != "" {
+			fields[field] = value
+		}
+	}
+	return fields
+}
+
+// getLine returns the request in common or combined log format
+func (a *AccessLog) getLine(ctx mux.Context, start time.Time, status int) string {
+	user := "-"
+	if username, _, ok := ctx.Request().BasicAuth(); ok && username != "" {
+		user = username
+	}
+	size := "-"
+	if bytes := GetResponseSize(ctx); bytes > 0 {
+		size = fmt.Sprint(bytes)
+	}
+	line := fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s",
+		ctx.RemoteAddr(),
+		user,
+		start.Format("02/Jan/2006:15:04:05 -0700"),
+		ctx.Method(),
+		ctx.Request().URL.RequestURI(),
+		ctx.Request().Proto,
+		status,
+		size,
+	)
+	if a.config.Format == AccessLogFormatCombined {
+		line += fmt.Sprintf(" %q %q", ctx.Request().Referer(), ctx.Request().UserAgent())
+	}
+	return line
+}
@@ -0,0 +1,74 @@
+package http_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alauda/bergamot/http"
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/alauda/bergamot/log"
+	"github.com/alauda/bergamot/loggo"
+)
+
+type recordLogger struct {
+	log.EmptyLogger
+	fields []loggo.Fields
+	lines  []string
+}
+
+func (r *recordLogger) StInfo(message string, fields loggo.Fields) {
+	r.fields = append(r.fields, fields)
+}
+
+func (r *recordLogger) StError(message string, fields loggo.Fields) {
+	r.fields = append(r.fields, fields)
+}
+
+func (r *recordLogger) Infof(format string, args ...interface{}) {
+	r.lines = append(r.lines, args[0].(string))
+}
+
+func TestAccessLog(t *testing.T) {
+	logger := &recordLogger{}
+	server := http.NewServer(http.Config{
+		AddLog: true,
+		LogFunc: http.NewAccessLog(http.AccessLogConfig{
+			Fields:       []string{http.AccessLogStatus, http.AccessLogBytes, http.AccessLogPath},
+			ExcludePaths: []string{"/_ping"},
+		}, logger).Serve,
+	}, log.EmptyLogger{}).Init()
+	server.GetApp().Get("/test", func(ctx mux.Context) {
+		ctx.SetStatusCode(201)
+		ctx.WriteString("created")
+	})
+	server.GetApp().Get("/_ping", func(ctx mux.Context) {})
+	server.Boot()
+
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/_ping", nil))
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	if len(logger.fields) != 1 {
+		t.Fatalf("expected 1 log got %d: %v", len(logger.fields), logger.fields)
+	}
+	fields := logger.fields[0]
+	if fields["status"] != 201 || fields["bytes"] != int64(7) || fields["path"] != "/test" || len(fields) != 3 {
+		t.Errorf("unexpected fields: %v", fields)
+	}
+
+	logger = &recordLogger{}
+	server = http.NewServer(http.Config{
+		AddLog:  true,
+		LogFunc: http.NewAccessLog(http.AccessLogConfig{Format: http.AccessLogFormatCombined}, logger).Serve,
+	}, log.EmptyLogger{}).Init()
+	server.GetApp().Get("/test", func(ctx mux.Context) {
+		ctx.WriteString("ok")
+	})
+	server.Boot()
+	request := httptest.NewRequest("GET", "/test?a=1", nil)
+	request.Header.Set("User-Agent", "test-agent")
+	server.ServeHTTP(httptest.NewRecorder(), request)
+	if len(logger.lines) != 1 || !strings.Contains(logger.lines[0], `"GET /test?a=1 HTTP/1.1" 200 2 "" "test-agent"`) {
+		t.Errorf("unexpected combined log: %v", logger.lines)
+	}
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/alauda/bergamot/auth"
+	"github.com/alauda/bergamot/http/mux"
+)
+
+// MiddlewareTypeAuth middleware type for routes that require authentication
+const MiddlewareTypeAuth = "auth"
+
+// AuthMiddleware authenticates requests storing the user under USER
+// requests without valid credentials get an unauthorized error
+type AuthMiddleware struct {
+	authenticator auth.Authenticator
+}
+
+// NewAuthMiddleware constructor function for AuthMiddleware
+// multiple authenticators are tried in order
+func NewAuthMiddleware(authenticators ...auth.Authenticator) *AuthMiddleware {
+	return &AuthMiddleware{authenticator: auth.Chain(authenticators)}
+}
+
+// Serve authenticates the request and calls the next handler
+// requests already authenticated are not authenticated again
+func (m *AuthMiddleware) Serve(ctx mux.Context) {
+	if ctx.Get(USER) != nil {
+		ctx.Next()
+		return
+	}
+	if _, err := Authenticate(ctx, m.authenticator); err != nil {
+		HandleUnauthorized(ctx, err)
+		return
+	}
+	ctx.Next()
+}
+
+// Authenticate authenticates the request and stores the user under USER
+func Authenticate(ctx mux.Context, authenticator auth.Authenticator) (*auth.User, error) {
+	user, err := authenticator.Authenticate(ctx.Request())
+	if err != nil {
+		return nil, err
+	}
+	ctx.Set(USER, user)
+	return user, nil
+}
+
+// HandleUnauthorized returns the authentication error
+// using the standard error format and stops the execution
+func HandleUnauthorized(ctx mux.Context, err error) {
+	ctx.StopExecution()
+	ctx.SetHeader("WWW-Authenticate", "Bearer")
+	Render(ctx, http.StatusUnauthorized, NewAlaudaError(err))
+}
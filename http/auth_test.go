@@ -0,0 +1,40 @@
+package http_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alauda/bergamot/auth"
+	"github.com/alauda/bergamot/http"
+	"github.com/alauda/bergamot/log"
+)
+
+func TestAuthHealthcheck(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Header   string
+		Expected int
+	}
+
+	table := []TestCase{
+		{"no credentials", "", 401},
+		{"invalid token", "Bearer invalid", 401},
+		{"valid token", "Bearer token", 200},
+	}
+
+	server := http.NewServer(http.Config{AddHealthCheck: true}, log.EmptyLogger{}).Init()
+	server.SetAuthenticator(auth.NewBearerAuthenticator(map[string]*auth.User{"token": {Name: "robot"}}))
+	server.Boot()
+
+	for i, test := range table {
+		request := httptest.NewRequest("GET", "/_auth_ping", nil)
+		if test.Header != "" {
+			request.Header.Set("Authorization", test.Header)
+		}
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != test.Expected {
+			t.Errorf("%d - %s -- expected status %d got %d: %s", i, test.Name, test.Expected, recorder.Code, recorder.Body.String())
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"github.com/alauda/bergamot/auth"
+	"github.com/alauda/bergamot/http/mux"
+)
+
+// WithPolicy requires the policy to access the routes of the endpoint
+// if the policy path is empty the endpoint path will be used
+func WithPolicy(policy auth.Policy) EndpointOption {
+	return func(e *endpoint) {
+		if policy.Path == "" {
+			policy.Path = e.path
+		}
+		e.policies = append(e.policies, policy)
+	}
+}
+
+// WithRoles requires any of the roles to access the routes of the endpoint
+func WithRoles(roles ...string) EndpointOption {
+	return WithPolicy(auth.Policy{Roles: roles})
+}
+
+// WithPermissions requires all the permissions to access the routes of the endpoint
+func WithPermissions(permissions ...string) EndpointOption {
+	return WithPolicy(auth.Policy{Permissions: permissions})
+}
+
+// SetAuthorizer sets the authorizer used to verify policies
+// defaults to auth.RoleAuthorizer
+func (h *Server) SetAuthorizer(authorizer auth.Authorizer) *Server {
+	h.authorizer = authorizer
+	return h
+}
+
+// SetPolicies replaces the policies loaded from configuration
+// policies declared by endpoints are kept, can be used while serving
+func (h *Server) SetPolicies(policies []auth.Policy) *Server {
+	h.configPolicies.Store(policies)
+	return h
+}
+
+// GetPolicies returns all the policies that apply to the method and route template
+func (h *Server) GetPolicies(method, route string) []auth.Policy {
+	var policies []auth.Policy
+	configPolicies, _ := h.configPolicies.Load().([]auth.Policy)
+	for _, collection := range [][]auth.Policy{h.policies, configPolicies} {
+		for _, policy := range collection {
+			if policy.Matches(method, route) {
+				policies = append(policies, policy)
+			}
+		}
+	}
+	return policies
+}
+
+// authorize verifies the request satisfies the policies of its route
+// runs after the route middlewares, so the user set by any of them is used,
+// otherwise authenticates the request using the authenticator
+func (h *Server) authorize(ctx mux.Context) {
+	configPolicies, _ := h.configPolicies.Load().([]auth.Policy)
+	if len(h.policies) == 0 && len(configPolicies) == 0 {
+		ctx.Next()
+		return
+	}
+	policies := h.GetPolicies(ctx.Method(), GetRouteTemplate(ctx))
+	if len(policies) == 0 {
+		ctx.Next()
+		return
+	}
+	if ctx.Get(USER) == nil {
+		if h.authenticator == nil {
+			HandleUnauthorized(ctx, auth.ErrNoCredentials)
+			return
+		}
+		if _, err := Authenticate(ctx, h.authenticator); err != nil {
+			HandleUnauthorized(ctx, err)
+			return
+		}
+	}
+	c := Handler{}.GetContext(ctx, false)
+	for _, policy := range policies {
+		if err := h.authorizer.Authorize(c, policy); err != nil {
+			ctx.StopExecution()
+			Render(ctx, getErrorStatusCode(err), NewAlaudaError(err))
+			return
+		}
+	}
+	ctx.Next()
+}
@@ -0,0 +1,102 @@
+package http_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alauda/bergamot/auth"
+	"github.com/alauda/bergamot/http"
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/alauda/bergamot/log"
+)
+
+func TestAuthorization(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Method   string
+		Path     string
+		Token    string
+		Expected int
+	}
+
+	table := []TestCase{
+		{"no credentials", "GET", "/v1/users/1", "", 401},
+		{"missing role", "GET", "/v1/users/1", "viewer", 403},
+		{"any role", "GET", "/v1/users/1", "admin", 200},
+		{"config policy denied", "DELETE", "/v1/users/1", "admin", 403},
+		{"config policy allowed", "DELETE", "/v1/users/1", "root", 200},
+		{"no policy", "GET", "/v1/status", "", 200},
+	}
+
+	server := http.NewServer(http.Config{}, log.EmptyLogger{}).Init()
+	server.SetAuthenticator(auth.NewBearerAuthenticator(map[string]*auth.User{
+		"viewer": {Name: "viewer", Roles: []string{"viewer"}},
+		"admin":  {Name: "admin", Roles: []string{"admin"}},
+		"root":   {Name: "root", Roles: []string{"admin"}, Permissions: []string{"users:delete"}},
+	}))
+	server.SetPolicies([]auth.Policy{{Path: "/v1/users/:id", Methods: []string{"DELETE"}, Permissions: []string{"users:delete"}}})
+	server.AddVersionEndpointFunc(1, "/users", func(router mux.Router, server *http.Server) {
+		router.Get("/:id", func(ctx mux.Context) { ctx.WriteString("user") })
+		router.Delete("/:id", func(ctx mux.Context) { ctx.WriteString("deleted") })
+	}, http.WithRoles("admin", "owner"))
+	server.AddVersionEndpointFunc(1, "/status", func(router mux.Router, server *http.Server) {
+		router.Get("", func(ctx mux.Context) { ctx.WriteString("ok") })
+	})
+	server.Boot()
+
+	for i, test := range table {
+		request := httptest.NewRequest(test.Method, test.Path, nil)
+		if test.Token != "" {
+			request.Header.Set("Authorization", "Bearer "+test.Token)
+		}
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != test.Expected {
+			t.Errorf("%d - %s -- expected status %d got %d: %s", i, test.Name, test.Expected, recorder.Code, recorder.Body.String())
+		}
+	}
+}
+
+// customAuth authenticates requests with the X-User header
+// without using SetAuthenticator
+type customAuth struct{}
+
+func (customAuth) Serve(ctx mux.Context) {
+	if name := ctx.RequestHeader("X-User"); name != "" {
+		ctx.Set(http.USER, &auth.User{Name: name, Roles: []string{name}})
+	}
+	ctx.Next()
+}
+
+func TestAuthorizationWithAuthMiddleware(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		User     string
+		Expected int
+	}
+
+	table := []TestCase{
+		{"no user", "", 401},
+		{"missing role", "viewer", 403},
+		{"role", "admin", 200},
+	}
+
+	server := http.NewServer(http.Config{}, log.EmptyLogger{}).Init()
+	server.AddMiddleware(customAuth{}, "auth")
+	server.AddVersionEndpointFunc(1, "/admin", func(router mux.Router, server *http.Server) {
+		router.Get("", func(ctx mux.Context) { ctx.WriteString("ok") })
+	}, http.WithMiddlewares("auth"), http.WithRoles("admin"))
+	server.Boot()
+
+	for i, test := range table {
+		request := httptest.NewRequest("GET", "/v1/admin", nil)
+		if test.User != "" {
+			request.Header.Set("X-User", test.User)
+		}
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != test.Expected {
+			t.Errorf("%d - %s -- expected status %d got %d: %s", i, test.Name, test.Expected, recorder.Code, recorder.Body.String())
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+
+	"github.com/alauda/bergamot/errors"
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/alauda/bergamot/validate"
+)
+
+// DecodeBody decodes the JSON or form body into body and validates it
+// using the validate tags. body should be a pointer to a struct.
+// Returns an invalid_args error with all the failing fields, on success
+// the body is stored under BODY and added to the context by GetContext
+func (Handler) DecodeBody(ctx mux.Context, body interface{}) error {
+	if err := decodeBody(ctx, body); err != nil {
+		return err
+	}
+	if err := validate.Struct(body); err != nil {
+		return err
+	}
+	ctx.Set(BODY, body)
+	return nil
+}
+
+func decodeBody(ctx mux.Context, body interface{}) error {
+	contentType, _, _ := mime.ParseMediaType(ctx.RequestHeader("Content-Type"))
+	switch contentType {
+	case "application/x-www-form-urlencoded", "multipart/form-data":
+		if err := ctx.ReadForm(body); err != nil {
+			return errors.New(validate.Source, errors.ErrorCodeInvalidArgs).SetMessage("Invalid form body: %v", err)
+		}
+		return nil
+	}
+	err := ctx.ReadJSON(body)
+	if err == nil {
+		return nil
+	}
+	alaudaErr := errors.New(validate.Source, errors.ErrorCodeInvalidArgs)
+	if typeErr, ok := err.(*json.UnmarshalTypeError); ok && typeErr.Field != "" {
+		return alaudaErr.AddFieldError(typeErr.Field, fmt.Sprintf("Expected %s.", typeErr.Type))
+	}
+	return alaudaErr.SetMessage("Invalid JSON body: %v", err)
+}
@@ -0,0 +1,59 @@
+package http_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alauda/bergamot/contexts"
+	"github.com/alauda/bergamot/http"
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/alauda/bergamot/log"
+)
+
+type createUser struct {
+	Name  string `json:"name" form:"name" validate:"required"`
+	Email string `json:"email" form:"email" validate:"regexp=^[^@]+@[^@]+$"`
+}
+
+func TestDecodeBody(t *testing.T) {
+	type TestCase struct {
+		Name        string
+		ContentType string
+		Body        string
+		Expected    int
+		Contains    string
+	}
+
+	table := []TestCase{
+		{"valid json", "application/json", `{"name":"bob","email":"bob@example.com"}`, 201, "bob"},
+		{"valid form", "application/x-www-form-urlencoded", "name=alice", 201, "alice"},
+		{"invalid json", "application/json", `{"name":`, 400, "invalid_args"},
+		{"wrong type", "application/json", `{"name":1}`, 400, `"name"`},
+		{"invalid fields", "application/json", `{"email":"bob"}`, 400, `"email"`},
+	}
+
+	server := http.NewServer(http.Config{}, log.EmptyLogger{}).Init()
+	server.AddVersionEndpointFunc(1, "/users", func(router mux.Router, server *http.Server) {
+		router.Post("", func(ctx mux.Context) {
+			handler := http.Handler{}
+			if err := handler.DecodeBody(ctx, &createUser{}); err != nil {
+				handler.HandleError(err, ctx, log.EmptyLogger{})
+				return
+			}
+			body := contexts.GetBody(handler.GetContext(ctx, false)).(*createUser)
+			ctx.JSON(201, body)
+		})
+	})
+	server.Boot()
+
+	for i, test := range table {
+		request := httptest.NewRequest("POST", "/v1/users", strings.NewReader(test.Body))
+		request.Header.Set("Content-Type", test.ContentType)
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != test.Expected || !strings.Contains(recorder.Body.String(), test.Contains) {
+			t.Errorf("%d - %s -- expected status %d with %s got %d: %s", i, test.Name, test.Expected, test.Contains, recorder.Code, recorder.Body.String())
+		}
+	}
+}
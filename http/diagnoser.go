@@ -1,8 +1,10 @@
 package http
 
 import (
+	"net/http"
+
 	"github.com/alauda/bergamot/diagnose"
-	iris "gopkg.in/kataras/iris.v6"
+	"github.com/alauda/bergamot/http/mux"
 )
 
 // DiagnoseRouter struct to add a route
@@ -18,8 +20,8 @@ func NewDiagnoser(checker *diagnose.HealthChecker) *DiagnoseRouter {
 }
 
 // AddRoutes will add a route for diagnose endpoint
-func (h *DiagnoseRouter) AddRoutes(router *iris.Router, server *Server) {
-	router.Any("", func(ctx *iris.Context) {
-		ctx.JSON(iris.StatusOK, h.Check())
+func (h *DiagnoseRouter) AddRoutes(router mux.Router, server *Server) {
+	router.Any("", func(ctx mux.Context) {
+		ctx.JSON(http.StatusOK, h.Check())
 	})
 }
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/alauda/bergamot/auth"
+	"github.com/alauda/bergamot/http/mux"
+)
+
+// endpoint options of an endpoint being added
+type endpoint struct {
+	path     string
+	policies []auth.Policy
+	kinds    []string
+}
+
+// EndpointOption option for AddEndpoint and AddVersionEndpoint
+type EndpointOption func(e *endpoint)
+
+// applyEndpointOptions applies the options of an endpoint and stores its policies
+func (h *Server) applyEndpointOptions(path string, opts []EndpointOption) *endpoint {
+	e := &endpoint{path: path}
+	for _, opt := range opts {
+		opt(e)
+	}
+	h.policies = append(h.policies, e.policies...)
+	return e
+}
+
+// WithMiddlewares applies the middlewares of the types to all the routes of the endpoint
+// ordered by priority together with the global middlewares
+func WithMiddlewares(kinds ...string) EndpointOption {
+	return func(e *endpoint) {
+		e.kinds = append(e.kinds, kinds...)
+	}
+}
+
+// endpointRouter returns the router of an endpoint
+// recording the middleware kinds of its routes for Boot
+func (h *Server) endpointRouter(router mux.Router, e *endpoint) mux.Router {
+	if len(e.kinds) == 0 {
+		return router
+	}
+	return kindsRouter{Router: router, server: h, kinds: e.kinds}
+}
+
+// kindsRouter mux.Router recording the middleware kinds of the routes
+type kindsRouter struct {
+	mux.Router
+	server *Server
+	kinds  []string
+}
+
+func (r kindsRouter) Handle(method, path string, handlers ...mux.HandlerFunc) mux.RouteInfo {
+	route := r.Router.Handle(method, path, handlers...)
+	r.server.routeKinds[route.Method+" "+route.Path] = r.kinds
+	return route
+}
+
+func (r kindsRouter) Get(path string, handlers ...mux.HandlerFunc) mux.RouteInfo {
+	return r.Handle(http.MethodGet, path, handlers...)
+}
+func (r kindsRouter) Post(path string, handlers ...mux.HandlerFunc) mux.RouteInfo {
+	return r.Handle(http.MethodPost, path, handlers...)
+}
+func (r kindsRouter) Put(path string, handlers ...mux.HandlerFunc) mux.RouteInfo {
+	return r.Handle(http.MethodPut, path, handlers...)
+}
+func (r kindsRouter) Patch(path string, handlers ...mux.HandlerFunc) mux.RouteInfo {
+	return r.Handle(http.MethodPatch, path, handlers...)
+}
+func (r kindsRouter) Delete(path string, handlers ...mux.HandlerFunc) mux.RouteInfo {
+	return r.Handle(http.MethodDelete, path, handlers...)
+}
+
+func (r kindsRouter) Any(path string, handlers ...mux.HandlerFunc) {
+	for _, method := range mux.AnyMethods {
+		r.Handle(method, path, handlers...)
+	}
+}
+
+func (r kindsRouter) Party(prefix string, handlers ...mux.HandlerFunc) mux.Router {
+	return kindsRouter{Router: r.Router.Party(prefix, handlers...), server: r.server, kinds: r.kinds}
+}
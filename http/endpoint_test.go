@@ -0,0 +1,61 @@
+package http_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alauda/bergamot/http"
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/alauda/bergamot/log"
+)
+
+// traceMiddleware appends its name to the X-Trace response header
+type traceMiddleware string
+
+func (m traceMiddleware) Serve(ctx mux.Context) {
+	ctx.ResponseWriter().Header().Add("X-Trace", string(m))
+	ctx.Next()
+}
+
+func TestEndpointMiddlewares(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Path     string
+		Expected string
+	}
+
+	table := []TestCase{
+		{"global only", "/v1/public", "first,global"},
+		{"kinds by priority", "/v1/admin", "first,auth,global,admin,last"},
+		{"single kind", "/users", "first,auth,global"},
+	}
+
+	server := http.NewServer(http.Config{}, log.EmptyLogger{}).Init()
+	server.AddMiddleware(traceMiddleware("global"))
+	server.AddMiddlewarePriority(traceMiddleware("first"), http.PriorityFirst, http.MiddlewareTypeAll)
+	server.AddMiddlewarePriority(traceMiddleware("last"), http.PriorityLast, "admin")
+	server.AddMiddleware(traceMiddleware("admin"), "admin")
+	server.AddMiddlewarePriority(traceMiddleware("auth"), -1, "auth")
+
+	handler := func(router mux.Router, server *http.Server) {
+		router.Get("", func(ctx mux.Context) { ctx.WriteString("ok") })
+	}
+	server.AddVersionEndpointFunc(1, "/public", handler)
+	server.AddVersionEndpointFunc(1, "/admin", handler, http.WithMiddlewares("admin", "auth"))
+	server.AddEndpoint("/users", http.AddRoutesFunc(handler), http.WithMiddlewares("auth"))
+	server.Boot()
+
+	for i, test := range table {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest("GET", test.Path, nil))
+		if trace := strings.Join(recorder.Header()["X-Trace"], ","); trace != test.Expected {
+			t.Errorf("%d - %s -- expected %s got %s", i, test.Name, test.Expected, trace)
+		}
+	}
+
+	infos := server.Middlewares()
+	if len(infos) != 5 || infos[0].Kind != "*" || infos[0].Priority != http.PriorityFirst || infos[4].Kind != "auth" {
+		t.Errorf("unexpected middlewares: %+v", infos)
+	}
+}
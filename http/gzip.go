@@ -0,0 +1,175 @@
+package http
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultGzipMinSize responses smaller than this are not compressed
+const DefaultGzipMinSize = 1024
+
+var gzipWriters = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(nil)
+	},
+}
+
+// gzipResponseWriter compresses the response body once it reaches the minimum size
+// the status code and the first bytes are kept until then
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minSize int
+	status  int
+	buffer  []byte
+	started bool
+	gzip    *gzip.Writer
+}
+
+// WriteHeader keeps the status code until the response is started
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if !w.started {
+		w.status = status
+	}
+}
+
+// Write buffers the body until reaching the minimum size
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if w.started {
+		if w.gzip != nil {
+			return w.gzip.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	w.buffer = append(w.buffer, b...)
+	if len(w.buffer) >= w.minSize {
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// start writes the headers and the buffered body
+// compressing the response if possible
+func (w *gzipResponseWriter) start(compress bool) error {
+	w.started = true
+	header := w.Header()
+	if compress && header.Get("Content-Encoding") == "" && isCompressible(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		w.gzip = gzipWriters.Get().(*gzip.Writer)
+		w.gzip.Reset(w.ResponseWriter)
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if len(w.buffer) == 0 {
+		return nil
+	}
+	buffer := w.buffer
+	w.buffer = nil
+	_, err := w.Write(buffer)
+	return err
+}
+
+// Flush starts the response without compression if it did not reach the minimum size
+// and flushes the compressed and the underlying writers
+func (w *gzipResponseWriter) Flush() {
+	if !w.started {
+		w.start(false)
+	}
+	if w.gzip != nil {
+		w.gzip.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close writes the rest of the response
+func (w *gzipResponseWriter) Close() error {
+	if !w.started {
+		return w.start(false)
+	}
+	if w.gzip == nil {
+		return nil
+	}
+	err := w.gzip.Close()
+	gzipWriters.Put(w.gzip)
+	w.gzip = nil
+	return err
+}
+
+// Hijack hijacks the underlying writer if supported
+func (w *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		w.started = true
+		return hijacker.Hijack()
+	}
+	return nil, nil, errors.New("hijack is not supported by this ResponseWriter")
+}
+
+// CloseNotify returns the close notification channel of the underlying writer
+func (w *gzipResponseWriter) CloseNotify() <-chan bool {
+	if notifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+// Push pushes using the underlying writer if supported
+func (w *gzipResponseWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// isCompressible returns false for streams and already compressed content
+func isCompressible(contentType string) bool {
+	for _, prefix := range []string{ContentTypeSSE, "image/", "video/", "audio/", "application/zip", "application/gzip", "application/x-gzip"} {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// acceptsGzip returns true if the client accepts gzip responses
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		params := strings.Split(part, ";")
+		if strings.TrimSpace(params[0]) != "gzip" {
+			continue
+		}
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(param[2:], 64)
+				return err == nil && q > 0
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compressResponse router wrapper that compresses responses using gzip
+func (h *Server) compressResponse(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		writer := &gzipResponseWriter{ResponseWriter: w, minSize: h.config.GzipMinSize}
+		defer writer.Close()
+		next.ServeHTTP(writer, r)
+	})
+}
@@ -2,12 +2,12 @@ package http
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/alauda/bergamot/contexts"
 	"github.com/alauda/bergamot/errors"
+	"github.com/alauda/bergamot/http/mux"
 	"github.com/alauda/bergamot/log"
-
-	iris "gopkg.in/kataras/iris.v6"
 )
 
 // Handler base handler with common shared methods
@@ -16,15 +16,20 @@ type Handler struct {
 }
 
 const (
-	// USER constant key for user in iris.Context
+	// USER constant key for user in mux.Context
 	USER = "USER"
+	// REQUESTID constant key for the request ID in mux.Context
+	REQUESTID = "REQUEST_ID"
+	// BODY constant key for the decoded body in mux.Context
+	BODY = "BODY"
 )
 
 // GetContext get context with predefined keys
-func (Handler) GetContext(ctx *iris.Context, attach bool) context.Context {
-	var parent context.Context
+// attach uses the context of the request as parent
+func (Handler) GetContext(ctx mux.Context, attach bool) context.Context {
+	parent := context.Background()
 	if attach {
-		parent = ctx
+		parent = ctx.Request().Context()
 	}
 	// string
 	c := contexts.SetPath(parent, ctx.Path())
@@ -35,51 +40,49 @@ func (Handler) GetContext(ctx *iris.Context, attach bool) context.Context {
 	// user
 	c = contexts.SetUser(c, ctx.Get(USER))
 
+	// body decoded using DecodeBody
+	if body := ctx.Get(BODY); body != nil {
+		c = contexts.SetBody(c, body)
+	}
+
+	// string
+	if requestID, _ := ctx.Get(REQUESTID).(string); requestID != "" {
+		c = contexts.SetRequestID(c, requestID)
+	}
+
 	// URL arguments
-	arguments := map[string]string{}
-	ctx.VisitValues(func(key string, value interface{}) {
-		if val, ok := value.(string); ok {
-			arguments[key] = val
-		}
-	})
-	c = contexts.SetArgs(c, arguments)
-	return c
+	return contexts.SetArgs(c, ctx.Params())
 }
 
 // HandleError Function to handle errors and return a message
-func (Handler) HandleError(err error, ctx *iris.Context, log log.Logger) {
+// rendered in the format negotiated with the Accept header
+func (Handler) HandleError(err error, ctx mux.Context, log log.Logger) {
 	status := getErrorStatusCode(err)
 	log.Debugf("Error: %v - returning status: %d", err, status)
-	ctx.JSON(
-		status,
-		NewAlaudaError(err),
-	)
+	Render(ctx, status, NewAlaudaError(err))
 }
 
 // HandleErrors Function to handle errors and return a message
-func (Handler) HandleErrors(errs []error, ctx *iris.Context, log log.Logger) bool {
+func (Handler) HandleErrors(errs []error, ctx mux.Context, log log.Logger) bool {
 	status := getErrorsStatusCode(errs)
 	if status == 0 {
 		return false
 	}
 	log.Debugf("Error: %v - returning status: %d", errs, status)
-	ctx.JSON(
-		status,
-		NewAlaudaError(errs...),
-	)
+	Render(ctx, status, NewAlaudaError(errs...))
 	return true
 }
 
 func getErrorsStatusCode(errs []error) int {
 	if errs == nil || len(errs) == 0 {
-		return iris.StatusInternalServerError
+		return http.StatusInternalServerError
 	}
 	for _, e := range errs {
 		if e != nil {
 			return getErrorStatusCode(e)
 		}
 	}
-	return iris.StatusInternalServerError
+	return http.StatusInternalServerError
 }
 
 func getErrorStatusCode(err error) int {
@@ -88,7 +91,7 @@ func getErrorStatusCode(err error) int {
 			return alaudaErr.StatusCode
 		}
 	}
-	return iris.StatusInternalServerError
+	return http.StatusInternalServerError
 }
 
 // AlaudaError structure to represent alauda's standard error format
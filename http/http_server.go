@@ -1,28 +1,41 @@
 package http
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/alauda/bergamot/auth"
+	"github.com/alauda/bergamot/http/mux"
 	"github.com/alauda/bergamot/log"
 	"github.com/alauda/bergamot/loggo"
+	"github.com/alauda/bergamot/metrics"
 
-	"gopkg.in/kataras/iris.v6"
-	"gopkg.in/kataras/iris.v6/adaptors/cors"
-	"gopkg.in/kataras/iris.v6/adaptors/httprouter"
+	"github.com/rs/cors"
 )
 
 // Router interface for http endpoints
 type Router interface {
-	AddRoutes(router *iris.Router, server *Server)
+	AddRoutes(router mux.Router, server *Server)
 }
 
 // AddRoutesFunc a function to add a route
-type AddRoutesFunc func(router *iris.Router, server *Server)
+type AddRoutesFunc func(router mux.Router, server *Server)
+
+// AddRoutes calls the function, satisfies the Router interface
+func (f AddRoutesFunc) AddRoutes(router mux.Router, server *Server) {
+	f(router, server)
+}
 
 // Middleware adding middleware
 type Middleware interface {
-	Serve(ctx *iris.Context)
+	Serve(ctx mux.Context)
 }
 
 // Config configuration for the HTTP server
@@ -30,13 +43,32 @@ type Config struct {
 	Host               string
 	Port               string
 	AddLog             bool
-	LogFunc            iris.HandlerFunc
+	LogFunc            mux.HandlerFunc
+	AddRequestID       bool
+	AddRecovery        bool
 	AddHealthCheck     bool
 	TreatNotFoundError bool
-	NotFoundFunc       iris.HandlerFunc
+	NotFoundFunc       mux.HandlerFunc
 	Component          string
 	MaxReadBufferSize  int
 	AllowedOrigins     []string
+	// CertFile and KeyFile paths to serve using TLS
+	CertFile string
+	KeyFile  string
+	// DrainDelay time to keep serving with a failing healthcheck
+	// before closing the listener when shutting down
+	DrainDelay time.Duration
+	// AddOpenAPI serves the OpenAPI document of each version at /v{n}/openapi.json
+	AddOpenAPI bool
+	// Gzip compresses responses of at least GzipMinSize bytes
+	// for clients that accept it, GzipMinSize defaults to DefaultGzipMinSize
+	Gzip        bool
+	GzipMinSize int
+	// Metrics client used by AddRecovery to count panics, can be nil
+	Metrics metrics.Client
+	// TrustedProxies proxies allowed to set the client IP
+	// using the X-Forwarded-For header
+	TrustedProxies []*net.IPNet
 }
 
 // SaneDefaults verifies the options and sets some sane defaults if
@@ -48,17 +80,23 @@ func (c Config) SaneDefaults() Config {
 	if len(c.AllowedOrigins) == 0 {
 		c.AllowedOrigins = []string{"*"}
 	}
+	if c.GzipMinSize <= 0 {
+		c.GzipMinSize = DefaultGzipMinSize
+	}
 	return c
 }
 
-// GetIrisOptions returns the options for the iris http server
-func (c Config) GetIrisOptions() []iris.OptionSetter {
-	return []iris.OptionSetter{
-		iris.OptionMaxHeaderBytes(c.MaxReadBufferSize),
-	}
+// GetAddr returns the address to listen on
+func (c Config) GetAddr() string {
+	return c.Host + ":" + c.Port
+}
+
+// IsTLS returns true when the certificate and key files are set
+func (c Config) IsTLS() bool {
+	return c.CertFile != "" && c.KeyFile != ""
 }
 
-// GetCorsOptions return cors options for iris http router
+// GetCorsOptions return cors options for the http router
 func (c Config) GetCorsOptions() cors.Options {
 	return cors.Options{AllowedOrigins: c.AllowedOrigins}
 }
@@ -68,68 +106,147 @@ type Server struct {
 	config      Config
 	start       time.Time
 	log         log.Logger
-	iris        *iris.Framework
-	versions    map[int]*iris.Router
-	middlewares map[string][]Middleware
+	mux         *mux.Mux
+	versions    map[int]mux.Router
+	middlewares map[string][]middlewareEntry
+	// routeKinds middleware kinds of the endpoint routes by method and path
+	routeKinds map[string][]string
+	// chains middlewares of the endpoint routes built on Boot by method and path
+	chains map[string][]mux.HandlerFunc
+	// globalChain middlewares of the other routes built on Boot
+	globalChain []mux.HandlerFunc
+	// authenticator used to verify credentials in _auth_ping
+	authenticator auth.Authenticator
+	authorizer    auth.Authorizer
+	// policies declared by endpoints
+	policies []auth.Policy
+	// configPolicies []auth.Policy replaced by SetPolicies
+	configPolicies atomic.Value
+	// docs route documentation by method and path
+	docs map[string]RouteDoc
+	// cors current *cors.Cors handler, replaced when changing allowed origins
+	cors atomic.Value
+	// handler mux wrapped by cors, gzip and the response size counter
+	handler http.Handler
+	// recovery middleware added by AddRecovery
+	recovery *RecoveryMiddleware
+	// server serving http after Start
+	server   *http.Server
+	lock     sync.Mutex
+	bootOnce sync.Once
+	// draining set to 1 when shutting down
+	draining int32
 }
 
 // NewServer constructor function for the HTTP server
 func NewServer(config Config, log log.Logger) *Server {
 	config = config.SaneDefaults()
+	router := mux.New()
+	router.TrustedProxies = config.TrustedProxies
 	return &Server{
 		config:      config,
 		log:         log,
-		iris:        iris.New(config.GetIrisOptions()...),
-		versions:    map[int]*iris.Router{},
-		middlewares: map[string][]Middleware{MiddlewareTypeAll: []Middleware{}},
+		mux:         router,
+		versions:    map[int]mux.Router{},
+		middlewares: map[string][]middlewareEntry{},
+		routeKinds:  map[string][]string{},
+		authorizer:  auth.NewRoleAuthorizer(),
 	}
 }
 
 // Init will setup any necessary data
 func (h *Server) Init() *Server {
-	h.iris.Adapt(
-		// Logging all errors
-		iris.DevLogger(),
-		// adding router
-		httprouter.New(),
+	// Cors wrapper to the entire application, allow all origins.
+	h.cors.Store(cors.New(h.config.GetCorsOptions()))
+	handler := http.Handler(http.HandlerFunc(h.serveCors))
+	if h.config.Gzip {
+		// Compressing responses inside the size counter
+		// so the response size is the compressed size
+		handler = h.compressResponse(handler)
+	}
+	// Keeps track of the response size for logs and metrics
+	h.handler = countResponseSize(handler)
 
-		// Cors wrapper to the entire application, allow all origins.
-		cors.New(h.config.GetCorsOptions()),
-	)
+	if h.config.AddRequestID {
+		// Adding request ID before any other handler
+		h.mux.Use(NewRequestIDMiddleware())
+	}
 
 	if h.config.AddHealthCheck {
 		// adding health check
-		h.iris.Any("/", h.Healthcheck)
-		h.iris.Any("/_ping", h.Healthcheck)
-		h.iris.Any("/_auth_ping", h.AuthHealthcheck)
+		h.mux.Any("/", h.serveMiddlewares, h.Healthcheck)
+		h.mux.Any("/_ping", h.serveMiddlewares, h.Healthcheck)
+		h.mux.Any("/_auth_ping", h.serveMiddlewares, h.AuthHealthcheck)
 	}
 
 	if h.config.AddLog && h.config.LogFunc != nil {
-		// Adding request logger middleware
-		h.iris.Use(h.config.LogFunc)
+		// Adding request logger middleware outside the recovery
+		// so requests that panic are logged with their 500 status
+		h.mux.Use(h.config.LogFunc)
+	}
+
+	if h.config.AddRecovery {
+		// Recovering from panics in any of the next handlers
+		h.recovery = NewRecoveryMiddleware(h.config.Component, h.log, h.config.Metrics)
+		h.mux.Use(h.recovery)
 	}
+
+	// Global and endpoint middlewares ordered by priority
+	h.mux.Use(mux.HandlerFunc(h.serveMiddlewares))
+
+	// Authorizing using the policies of each route
+	// after the middlewares that authenticate the request
+	h.mux.Use(mux.HandlerFunc(h.authorize))
+
 	if h.config.TreatNotFoundError && h.config.NotFoundFunc != nil {
 		// default error when requesting unexistent route
-		h.iris.OnError(iris.StatusNotFound, h.config.NotFoundFunc)
+		h.mux.NotFound = h.config.NotFoundFunc
 	}
 
 	return h
 }
 
+// SetMetricsClient sets the client used by AddRecovery to count panics
+// should be executed before the Start method
+func (h *Server) SetMetricsClient(client metrics.Client) *Server {
+	h.config.Metrics = client
+	if h.recovery != nil {
+		h.recovery.metrics = client
+	}
+	return h
+}
+
+// SetAllowedOrigins replaces the CORS allowed origins
+// can be used while serving
+func (h *Server) SetAllowedOrigins(origins []string) *Server {
+	config := h.config
+	config.AllowedOrigins = origins
+	config = config.SaneDefaults()
+	h.cors.Store(cors.New(config.GetCorsOptions()))
+	return h
+}
+
+func (h *Server) serveCors(w http.ResponseWriter, r *http.Request) {
+	h.cors.Load().(*cors.Cors).ServeHTTP(w, r, h.mux.ServeHTTP)
+}
+
 // AddVersion Adds a version number to the API route
 func (h *Server) AddVersion(version int) *Server {
 	if _, ok := h.versions[version]; !ok {
 		// adds /v1 or /v2 route
-		h.versions[version] = h.iris.Party(fmt.Sprintf("/v%d", version))
+		h.versions[version] = h.mux.Party(fmt.Sprintf("/v%d", version))
+		if h.config.AddOpenAPI {
+			h.versions[version].Get(OpenAPIPath, h.serveOpenAPI(version))
+		}
 	}
 	return h
 }
 
 // AddEndpoint ands a handler for the given relative path
 // should be executed before the Start method and after the Init method
-func (h *Server) AddEndpoint(relativePath string, handler Router) *Server {
-	router := h.iris.Party(relativePath)
-	handler.AddRoutes(router, h)
+func (h *Server) AddEndpoint(relativePath string, handler Router, opts ...EndpointOption) *Server {
+	e := h.applyEndpointOptions(relativePath, opts)
+	handler.AddRoutes(h.endpointRouter(h.mux.Party(relativePath), e), h)
 
 	return h
 }
@@ -137,67 +254,201 @@ func (h *Server) AddEndpoint(relativePath string, handler Router) *Server {
 // AddVersionEndpoint add a root endpoint to a version specific API
 // Used like AddEndpoint but will add on a specific version instead.
 // If the version was not created previously will then be created automatically
-func (h *Server) AddVersionEndpoint(version int, relativePath string, handler Router) *Server {
-	return h.AddVersionEndpointFunc(version, relativePath, handler.AddRoutes)
+func (h *Server) AddVersionEndpoint(version int, relativePath string, handler Router, opts ...EndpointOption) *Server {
+	return h.AddVersionEndpointFunc(version, relativePath, handler.AddRoutes, opts...)
 }
 
 // AddVersionEndpointFunc add a root endpoint to a version specific API
 // Used like AddEndpoint but will add on a specific version instead.
 // If the version was not created previously will then be created automatically
-func (h *Server) AddVersionEndpointFunc(version int, relativePath string, addRoutesFunc AddRoutesFunc) *Server {
+func (h *Server) AddVersionEndpointFunc(version int, relativePath string, addRoutesFunc AddRoutesFunc, opts ...EndpointOption) *Server {
 	h.AddVersion(version)
-	addRoutesFunc(h.versions[version].Party(relativePath), h)
+	e := h.applyEndpointOptions(fmt.Sprintf("/v%d%s", version, relativePath), opts)
+	addRoutesFunc(h.endpointRouter(h.versions[version].Party(relativePath), e), h)
 	return h
 }
 
 // Healthcheck healthcheck endpoint
-func (h *Server) Healthcheck(ctx *iris.Context) {
+// returns 503 while shutting down
+func (h *Server) Healthcheck(ctx mux.Context) {
+	if h.IsDraining() {
+		h.drainingHealthcheck(ctx)
+		return
+	}
 	ctx.WriteString(fmt.Sprintf("%s:%s", h.config.Component, time.Since(h.start)))
 }
 
-// AuthHealthcheck healthcheck endpoint
-func (h *Server) AuthHealthcheck(ctx *iris.Context) {
-	token := ctx.Request.Header.Get("Authorization")
+// SetAuthenticator sets the authenticator used to verify credentials
+// in the auth healthcheck and adds an AuthMiddleware for MiddlewareTypeAuth
+func (h *Server) SetAuthenticator(authenticator auth.Authenticator) *Server {
+	h.authenticator = authenticator
+	return h.AddMiddleware(NewAuthMiddleware(authenticator), MiddlewareTypeAuth)
+}
 
-	if token == "" {
-		ctx.SetStatusCode(iris.StatusUnauthorized)
-		ctx.WriteString(fmt.Sprintf("%s. You Unauthorized.", h.config.Component))
-	} else {
-		ctx.WriteString(fmt.Sprintf("%s. Your Authorization %v.", h.config.Component, token))
+// AuthHealthcheck healthcheck endpoint
+// verifies the credentials using the authenticator set by SetAuthenticator
+func (h *Server) AuthHealthcheck(ctx mux.Context) {
+	if h.IsDraining() {
+		h.drainingHealthcheck(ctx)
+		return
 	}
+	if h.authenticator == nil {
+		HandleUnauthorized(ctx, auth.NewUnauthorizedError("No authenticator configured."))
+		return
+	}
+	user, err := Authenticate(ctx, h.authenticator)
+	if err != nil {
+		HandleUnauthorized(ctx, err)
+		return
+	}
+	ctx.WriteString(fmt.Sprintf("%s. Authorized as %s.", h.config.Component, user.Name))
+}
+
+// GetApp returns the router of the server
+// routes can be registered directly before booting
+func (h *Server) GetApp() *mux.Mux {
+	return h.mux
+}
 
+// ServeHTTP serves a request, used for testing
+// Init and Boot should be called before serving
+func (h *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handler.ServeHTTP(w, r)
 }
 
-// GetApp returns the iris app, used for testing
-func (h *Server) GetApp() *iris.Framework {
-	return h.iris
+func (h *Server) drainingHealthcheck(ctx mux.Context) {
+	ctx.SetStatusCode(http.StatusServiceUnavailable)
+	ctx.WriteString(fmt.Sprintf("%s: shutting down", h.config.Component))
 }
 
 // Start will start serving the http server
 // this method will block while serving http
-func (h *Server) Start() {
+// and return nil after Shutdown is called
+func (h *Server) Start() error {
 	h.start = time.Now()
-	h.iris.Listen(h.config.Host + ":" + h.config.Port)
+	h.Boot()
+
+	server := &http.Server{
+		Addr:           h.config.GetAddr(),
+		Handler:        h.handler,
+		MaxHeaderBytes: h.config.MaxReadBufferSize,
+	}
+	h.lock.Lock()
+	if h.IsDraining() {
+		h.lock.Unlock()
+		return nil
+	}
+	h.server = server
+	h.lock.Unlock()
+
+	var err error
+	if h.config.IsTLS() {
+		err = server.ListenAndServeTLS(h.config.CertFile, h.config.KeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// Boot builds the middleware chains of all the routes
+// merging the global and the endpoint middlewares by priority.
+// Called by Start, can be used to serve requests without listening
+func (h *Server) Boot() *Server {
+	h.bootOnce.Do(func() {
+		h.globalChain = h.getHandlerFuncs(MiddlewareTypeAll)
+		h.chains = make(map[string][]mux.HandlerFunc, len(h.routeKinds))
+		for key, kinds := range h.routeKinds {
+			h.chains[key] = h.getHandlerFuncs(append([]string{MiddlewareTypeAll}, kinds...)...)
+		}
+	})
+	return h
+}
+
+// serveMiddlewares runs the middleware chain of the route built on Boot
+func (h *Server) serveMiddlewares(ctx mux.Context) {
+	chain, ok := h.chains[ctx.Method()+" "+ctx.Route()]
+	if !ok {
+		chain = h.globalChain
+	}
+	mux.Chain(chain...)(ctx)
+}
+
+// Shutdown gracefully stops the server: healthchecks start failing,
+// after DrainDelay the listener is closed and waits for in-flight requests
+// to finish or until the context is done
+func (h *Server) Shutdown(ctx context.Context) error {
+	h.lock.Lock()
+	atomic.StoreInt32(&h.draining, 1)
+	server := h.server
+	h.lock.Unlock()
+	if server == nil {
+		return nil
+	}
+	if h.config.DrainDelay > 0 {
+		select {
+		case <-time.After(h.config.DrainDelay):
+		case <-ctx.Done():
+		}
+	}
+	return server.Shutdown(ctx)
+}
+
+// IsDraining returns true when the server is shutting down
+func (h *Server) IsDraining() bool {
+	return atomic.LoadInt32(&h.draining) == 1
 }
 
 const (
-	// MiddlewareTypeAll special type
+	// MiddlewareTypeAll special type for middlewares applied to all routes
 	MiddlewareTypeAll = "*"
 )
 
-// AddMiddleware adds a middleware for the given types
+const (
+	// PriorityFirst priority of middlewares that should run before the others
+	PriorityFirst = -1000
+	// PriorityDefault priority of middlewares added using AddMiddleware
+	PriorityDefault = 0
+	// PriorityLast priority of middlewares that should run after the others
+	PriorityLast = 1000
+)
+
+// middlewareEntry middleware added for a type
+type middlewareEntry struct {
+	mw       Middleware
+	priority int
+}
+
+// MiddlewareInfo description of an added middleware
+type MiddlewareInfo struct {
+	Kind     string
+	Name     string
+	Priority int
+}
+
+// AddMiddleware adds a middleware for the given types with the default priority
+// middlewares added without types or with MiddlewareTypeAll are applied to all routes on Boot,
+// the other types are applied to the endpoints added using WithMiddlewares.
+// The global and endpoint middlewares of a route run in a single chain ordered by priority
 func (h *Server) AddMiddleware(mw Middleware, kinds ...string) *Server {
-	var (
-		collection []Middleware
-		ok         bool
-	)
-	kinds = append(kinds, MiddlewareTypeAll)
+	return h.AddMiddlewarePriority(mw, PriorityDefault, kinds...)
+}
+
+// AddMiddlewarePriority adds a middleware for the given types
+// middlewares with lower priority run first, middlewares with
+// the same priority run in the order they were added
+func (h *Server) AddMiddlewarePriority(mw Middleware, priority int, kinds ...string) *Server {
+	if len(kinds) == 0 {
+		kinds = []string{MiddlewareTypeAll}
+	}
 	for _, k := range kinds {
-		if collection, ok = h.middlewares[k]; !ok {
-			collection = make([]Middleware, 0, 2)
-		}
-		collection = append(collection, mw)
-		h.middlewares[k] = collection
+		entries := append(h.middlewares[k], middlewareEntry{mw: mw, priority: priority})
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].priority < entries[j].priority
+		})
+		h.middlewares[k] = entries
 	}
 	return h
 }
@@ -210,30 +461,69 @@ func (h *Server) AddMiddlewares(mws []Middleware, kinds ...string) *Server {
 	return h
 }
 
-// GetMiddlewares get all midlewares of a kind
+// GetMiddlewares get all midlewares of a kind in the order they run
 func (h *Server) GetMiddlewares(kind string) []Middleware {
-	return h.middlewares[kind]
+	entries := h.middlewares[kind]
+	mws := make([]Middleware, len(entries))
+	for i, entry := range entries {
+		mws[i] = entry.mw
+	}
+	return mws
+}
+
+// Middlewares returns all the added middlewares by type in the order they run
+func (h *Server) Middlewares() []MiddlewareInfo {
+	kinds := make([]string, 0, len(h.middlewares))
+	for k := range h.middlewares {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	var infos []MiddlewareInfo
+	for _, k := range kinds {
+		for _, entry := range h.middlewares[k] {
+			infos = append(infos, MiddlewareInfo{
+				Kind:     k,
+				Name:     fmt.Sprintf("%T", entry.mw),
+				Priority: entry.priority,
+			})
+		}
+	}
+	return infos
 }
 
 // GetMiddlewareHandlerFun returns all the handler functions of a middleware kind
-func (h *Server) GetMiddlewareHandlerFun(kind string) []iris.HandlerFunc {
+func (h *Server) GetMiddlewareHandlerFun(kind string) []mux.HandlerFunc {
 	return GetMiddlewareHandlerFunc(h.GetMiddlewares(kind)...)
 }
 
 // GetMiddlewaresDecorated gets all the handler functions of a collection of kinds and decorate the target function
-func (h *Server) GetMiddlewaresDecorated(handlerFunc iris.HandlerFunc, kinds ...string) []iris.HandlerFunc {
-	var mws []iris.HandlerFunc
+// the middlewares of all the kinds are ordered by priority
+func (h *Server) GetMiddlewaresDecorated(handlerFunc mux.HandlerFunc, kinds ...string) []mux.HandlerFunc {
+	return append(h.getHandlerFuncs(kinds...), handlerFunc)
+}
+
+// getHandlerFuncs returns the handler functions of the middlewares
+// of all the kinds ordered by priority, for the same priority
+// the middlewares of the first kinds run first
+func (h *Server) getHandlerFuncs(kinds ...string) []mux.HandlerFunc {
+	var entries []middlewareEntry
 	for _, k := range kinds {
-		mws = append(mws, h.GetMiddlewareHandlerFun(k)...)
+		entries = append(entries, h.middlewares[k]...)
 	}
-	mws = append(mws, handlerFunc)
-	return mws
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].priority < entries[j].priority
+	})
+	funcs := make([]mux.HandlerFunc, len(entries))
+	for i, entry := range entries {
+		funcs[i] = entry.mw.Serve
+	}
+	return funcs
 }
 
 // GetMiddlewareHandlerFunc get only the functions from middlewares
-func GetMiddlewareHandlerFunc(mws ...Middleware) []iris.HandlerFunc {
-	var funcs []iris.HandlerFunc
-	funcs = make([]iris.HandlerFunc, len(mws), len(mws)+1)
+func GetMiddlewareHandlerFunc(mws ...Middleware) []mux.HandlerFunc {
+	var funcs []mux.HandlerFunc
+	funcs = make([]mux.HandlerFunc, len(mws), len(mws)+1)
 	for i, mw := range mws {
 		funcs[i] = mw.Serve
 	}
@@ -241,38 +531,64 @@ func GetMiddlewareHandlerFunc(mws ...Middleware) []iris.HandlerFunc {
 }
 
 // DecorateHandlerFunc prepend all the given middlewares
-func DecorateHandlerFunc(handlerFunc iris.HandlerFunc, mws ...Middleware) []iris.HandlerFunc {
+func DecorateHandlerFunc(handlerFunc mux.HandlerFunc, mws ...Middleware) []mux.HandlerFunc {
 	return append(GetMiddlewareHandlerFunc(mws...), handlerFunc)
 }
 
 // NewStLogFunc returns a middleware print function using StLogger
-func NewStLogFunc(logger log.StLogger) iris.HandlerFunc {
-	return func(ctx *iris.Context) {
-		logger.StInfo("request", loggo.Fields{
+func NewStLogFunc(logger log.StLogger) mux.HandlerFunc {
+	return func(ctx mux.Context) {
+		fields := loggo.Fields{
 			"method": ctx.Method(),
 			"path":   ctx.Path(),
-			"params": ctx.ParamsSentence(),
-		})
+			"params": paramsSentence(ctx),
+		}
+		if requestID, _ := ctx.Get(REQUESTID).(string); requestID != "" {
+			fields["request_id"] = requestID
+		}
+		logger.StInfo("request", fields)
 		ctx.Next()
 	}
 }
 
 // NewStandardLogFunc returns a middleware print function using StandardLogger
-func NewStandardLogFunc(logger log.StandardLogger) iris.HandlerFunc {
-	return func(ctx *iris.Context) {
-		logger.Infof("[%s] - path: %s\tparams: %s", ctx.Method(), ctx.Path(), ctx.ParamsSentence())
+func NewStandardLogFunc(logger log.StandardLogger) mux.HandlerFunc {
+	return func(ctx mux.Context) {
+		if requestID, _ := ctx.Get(REQUESTID).(string); requestID != "" {
+			logger.Infof("[%s] - path: %s\tparams: %s\trequest_id: %s", ctx.Method(), ctx.Path(), paramsSentence(ctx), requestID)
+		} else {
+			logger.Infof("[%s] - path: %s\tparams: %s", ctx.Method(), ctx.Path(), paramsSentence(ctx))
+		}
 		ctx.Next()
 	}
 }
 
 // NewStructuredLogFunc returns a middleware print function using StructuredLogger
-func NewStructuredLogFunc(logger log.StructuredLogger) iris.HandlerFunc {
-	return func(ctx *iris.Context) {
-		logger.Info("request",
+func NewStructuredLogFunc(logger log.StructuredLogger) mux.HandlerFunc {
+	return func(ctx mux.Context) {
+		keyvals := []interface{}{
 			"method", ctx.Method(),
 			"path", ctx.Path(),
-			"params", ctx.ParamsSentence(),
-		)
+			"params", paramsSentence(ctx),
+		}
+		if requestID, _ := ctx.Get(REQUESTID).(string); requestID != "" {
+			keyvals = append(keyvals, "request_id", requestID)
+		}
+		logger.Info("request", keyvals...)
 		ctx.Next()
 	}
 }
+
+// paramsSentence returns the path parameters like id=1,name=a sorted by name
+func paramsSentence(ctx mux.Context) string {
+	params := ctx.Params()
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		keys[i] = k + "=" + params[k]
+	}
+	return strings.Join(keys, ",")
+}
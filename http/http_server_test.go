@@ -0,0 +1,88 @@
+package http_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alauda/bergamot/contexts"
+	"github.com/alauda/bergamot/http"
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/alauda/bergamot/log"
+)
+
+func TestServerShutdown(t *testing.T) {
+	server := http.NewServer(http.Config{
+		Host:           "127.0.0.1",
+		Port:           "0",
+		Component:      "test",
+		AddHealthCheck: true,
+	}, log.EmptyLogger{}).Init()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Start()
+	}()
+	// waiting for the server to start listening
+	time.Sleep(50 * time.Millisecond)
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Start should return nil after Shutdown got: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Start did not return after Shutdown")
+	}
+
+	// healthchecks fail while draining
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest("GET", "/_ping", nil))
+	if recorder.Code != 503 {
+		t.Errorf("expected 503 while draining got: %d", recorder.Code)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Header   string
+		Expected string
+	}
+
+	table := []TestCase{
+		{"from header", "abc", "abc"},
+		{"generated", "", ""},
+		{"invalid characters", "abc\r\nX-Injected: 1", ""},
+		{"too long", strings.Repeat("a", contexts.MaxRequestIDLength+1), ""},
+	}
+
+	var fromContext string
+	server := http.NewServer(http.Config{AddRequestID: true}, log.EmptyLogger{}).Init()
+	server.GetApp().Get("/test", func(ctx mux.Context) {
+		fromContext = contexts.GetRequestID(http.Handler{}.GetContext(ctx, false))
+		ctx.WriteString("ok")
+	})
+	server.Boot()
+
+	for i, test := range table {
+		request := httptest.NewRequest("GET", "/test", nil)
+		if test.Header != "" {
+			request.Header.Set(http.RequestIDHeader, test.Header)
+		}
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		requestID := recorder.Header().Get(http.RequestIDHeader)
+		if requestID == "" || (test.Expected != "" && requestID != test.Expected) || (test.Expected == "" && requestID == test.Header) {
+			t.Errorf("%d - %s -- expected request id %q got %q", i, test.Name, test.Expected, requestID)
+		}
+		if fromContext != requestID {
+			t.Errorf("%d - %s -- expected context request id %q got %q", i, test.Name, requestID, fromContext)
+		}
+	}
+}
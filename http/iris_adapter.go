@@ -0,0 +1,278 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/alauda/bergamot/http/mux"
+
+	iris "gopkg.in/kataras/iris.v6"
+)
+
+// routeTemplateKey key to cache the route template in iris.Context
+const routeTemplateKey = "ROUTE_TEMPLATE"
+
+// irisValuesKey key of the values set by iris handlers in mux.Context
+// given to the next iris handlers
+const irisValuesKey = "IRIS_VALUES"
+
+// internalKeys keys set by bergamot in iris.Context that are not URL arguments
+var internalKeys = map[string]struct{}{
+	USER:             {},
+	REQUESTID:        {},
+	BODY:             {},
+	routeTemplateKey: {},
+}
+
+// irisContext mux.Context implementation for iris
+type irisContext struct {
+	ctx *iris.Context
+}
+
+// NewMuxContext adapts an iris context to mux.Context
+// used to serve bergamot handlers and middlewares from iris apps
+func NewMuxContext(ctx *iris.Context) mux.Context {
+	return irisContext{ctx: ctx}
+}
+
+func (c irisContext) Request() *http.Request               { return c.ctx.Request }
+func (c irisContext) ResponseWriter() http.ResponseWriter  { return c.ctx.ResponseWriter }
+func (c irisContext) Method() string                       { return c.ctx.Method() }
+func (c irisContext) Path() string                         { return c.ctx.Path() }
+func (c irisContext) Param(name string) string             { return c.ctx.Param(name) }
+func (c irisContext) URLParam(name string) string          { return c.ctx.URLParam(name) }
+func (c irisContext) URLParams() map[string]string         { return c.ctx.URLParams() }
+func (c irisContext) RequestHeader(name string) string     { return c.ctx.RequestHeader(name) }
+func (c irisContext) RemoteAddr() string                   { return c.ctx.RemoteAddr() }
+func (c irisContext) ReadJSON(v interface{}) error         { return c.ctx.ReadJSON(v) }
+func (c irisContext) ReadForm(v interface{}) error         { return c.ctx.ReadForm(v) }
+func (c irisContext) SetHeader(name, value string)         { c.ctx.SetHeader(name, value) }
+func (c irisContext) SetStatusCode(status int)             { c.ctx.SetStatusCode(status) }
+func (c irisContext) StatusCode() int                      { return c.ctx.ResponseWriter.StatusCode() }
+func (c irisContext) Write(data []byte) (int, error)       { return c.ctx.Write(data) }
+func (c irisContext) WriteString(s string) (int, error)    { return c.ctx.WriteString(s) }
+func (c irisContext) JSON(status int, v interface{}) error { return c.ctx.JSON(status, v) }
+func (c irisContext) Flush()                               { c.ctx.ResponseWriter.Flush() }
+func (c irisContext) Set(key string, value interface{})    { c.ctx.Set(key, value) }
+func (c irisContext) Get(key string) interface{}           { return c.ctx.Get(key) }
+func (c irisContext) Next()                                { c.ctx.Next() }
+func (c irisContext) StopExecution()                       { c.ctx.StopExecution() }
+func (c irisContext) IsStopped() bool                      { return c.ctx.IsStopped() }
+
+// Route returns the template of the iris route that best matches the path
+// the result is cached in the context
+func (c irisContext) Route() string {
+	if template := c.ctx.GetString(routeTemplateKey); template != "" {
+		return template
+	}
+	segments := mux.SplitPath(c.ctx.Path())
+	var (
+		best      []string
+		bestScore = -1
+	)
+	c.ctx.Framework().Routes().Visit(func(route iris.RouteInfo) {
+		if route.Method() != c.ctx.Method() {
+			return
+		}
+		template := mux.SplitPath(route.Path())
+		if score := mux.MatchSegments(template, segments); score > bestScore {
+			best, bestScore = template, score
+		}
+	})
+	if best == nil {
+		return ""
+	}
+	template := "/" + strings.Join(best, "/")
+	c.ctx.Set(routeTemplateKey, template)
+	return template
+}
+
+// Params returns the path parameters
+// skipping the values set by bergamot
+func (c irisContext) Params() map[string]string {
+	params := map[string]string{}
+	c.ctx.VisitValues(func(key string, value interface{}) {
+		if _, ok := internalKeys[key]; ok {
+			return
+		}
+		if val, ok := value.(string); ok {
+			params[key] = val
+		}
+	})
+	return params
+}
+
+// IrisHandlerFunc adapts a mux.HandlerFunc to an iris.HandlerFunc
+func IrisHandlerFunc(handler mux.HandlerFunc) iris.HandlerFunc {
+	return func(ctx *iris.Context) {
+		handler(NewMuxContext(ctx))
+	}
+}
+
+// IrisHandlerFuncs adapts mux.HandlerFuncs to iris.HandlerFuncs
+func IrisHandlerFuncs(handlers ...mux.HandlerFunc) []iris.HandlerFunc {
+	funcs := make([]iris.HandlerFunc, len(handlers))
+	for i, handler := range handlers {
+		funcs[i] = IrisHandlerFunc(handler)
+	}
+	return funcs
+}
+
+// IrisMiddleware adapts a bergamot middleware to an iris.Handler
+func IrisMiddleware(mw Middleware) iris.Handler {
+	return IrisHandlerFunc(mw.Serve)
+}
+
+// irisRouter mux.Router implementation for iris
+type irisRouter struct {
+	router *iris.Router
+}
+
+// NewIrisRouter adapts an iris router to mux.Router
+// used to register bergamot routes in iris apps
+func NewIrisRouter(router *iris.Router) mux.Router {
+	return irisRouter{router: router}
+}
+
+func (r irisRouter) Handle(method, path string, handlers ...mux.HandlerFunc) mux.RouteInfo {
+	route := r.router.HandleFunc(method, path, IrisHandlerFuncs(handlers...)...)
+	return mux.RouteInfo{Method: route.Method(), Path: route.Path()}
+}
+
+func (r irisRouter) Get(path string, handlers ...mux.HandlerFunc) mux.RouteInfo {
+	return r.Handle(http.MethodGet, path, handlers...)
+}
+func (r irisRouter) Post(path string, handlers ...mux.HandlerFunc) mux.RouteInfo {
+	return r.Handle(http.MethodPost, path, handlers...)
+}
+func (r irisRouter) Put(path string, handlers ...mux.HandlerFunc) mux.RouteInfo {
+	return r.Handle(http.MethodPut, path, handlers...)
+}
+func (r irisRouter) Patch(path string, handlers ...mux.HandlerFunc) mux.RouteInfo {
+	return r.Handle(http.MethodPatch, path, handlers...)
+}
+func (r irisRouter) Delete(path string, handlers ...mux.HandlerFunc) mux.RouteInfo {
+	return r.Handle(http.MethodDelete, path, handlers...)
+}
+
+func (r irisRouter) Any(path string, handlers ...mux.HandlerFunc) {
+	for _, method := range mux.AnyMethods {
+		r.Handle(method, path, handlers...)
+	}
+}
+
+func (r irisRouter) Party(prefix string, handlers ...mux.HandlerFunc) mux.Router {
+	return irisRouter{router: r.router.Party(prefix, IrisHandlerFuncs(handlers...)...)}
+}
+
+func (r irisRouter) Use(mws ...mux.Middleware) {
+	for _, mw := range mws {
+		r.router.Use(IrisMiddleware(mw))
+	}
+}
+
+// IrisRouter interface for http endpoints registered using iris
+// mounted on the server using FromIrisRouter
+type IrisRouter interface {
+	AddRoutes(router *iris.Router, server *Server)
+}
+
+// IrisAddRoutesFunc a function to add routes using iris
+type IrisAddRoutesFunc func(router *iris.Router, server *Server)
+
+// AddRoutes calls the function, satisfies the IrisRouter interface
+func (f IrisAddRoutesFunc) AddRoutes(router *iris.Router, server *Server) {
+	f(router, server)
+}
+
+// FromIrisRouter adapts an iris endpoint to a Router used by AddEndpoint,
+// the routes registered in the iris router are added to the endpoint
+// with their iris handlers and middlewares.
+// Routes can also be added directly to the server using
+// FromIrisRouter(handler).AddRoutes(server.GetApp(), server)
+func FromIrisRouter(handler IrisRouter) Router {
+	return AddRoutesFunc(func(router mux.Router, server *Server) {
+		app := iris.New()
+		handler.AddRoutes(app.Router, server)
+		app.Routes().Visit(func(route iris.RouteInfo) {
+			router.Handle(route.Method(), route.Path(), serveIris(app, route.Middleware()))
+		})
+	})
+}
+
+// FromIrisHandlerFunc adapts an iris.HandlerFunc to a mux.HandlerFunc
+// can be used for the LogFunc and NotFoundFunc of the Config
+func FromIrisHandlerFunc(handler iris.HandlerFunc) mux.HandlerFunc {
+	return FromIrisHandlers(handler)
+}
+
+// FromIrisHandlers adapts iris handlers to a single mux.HandlerFunc
+// the next mux handlers run when the last iris handler calls ctx.Next
+func FromIrisHandlers(handlers ...iris.Handler) mux.HandlerFunc {
+	return serveIris(getIrisApp(), handlers)
+}
+
+// FromIrisMiddleware adapts an iris middleware to a bergamot middleware
+// used by AddMiddleware
+func FromIrisMiddleware(mw iris.Handler) Middleware {
+	return FromIrisHandlers(mw)
+}
+
+var (
+	irisApp     *iris.Framework
+	irisAppOnce sync.Once
+)
+
+// getIrisApp returns the iris app used to create the contexts of the iris handlers
+func getIrisApp() *iris.Framework {
+	irisAppOnce.Do(func() {
+		irisApp = iris.New()
+	})
+	return irisApp
+}
+
+// serveIris returns a mux.HandlerFunc running the iris handlers with an iris context
+// of the app, path parameters and values set by bergamot are copied to the iris context
+// and the values set by the iris handlers are copied back for the next mux handlers
+func serveIris(app *iris.Framework, handlers iris.Middleware) mux.HandlerFunc {
+	return func(ctx mux.Context) {
+		c := app.Router.Context.Acquire(ctx.ResponseWriter(), ctx.Request())
+		defer app.Router.Context.Release(c)
+		params := ctx.Params()
+		for k, v := range params {
+			c.Set(k, v)
+		}
+		values, _ := ctx.Get(irisValuesKey).(map[string]interface{})
+		for k, v := range values {
+			c.Set(k, v)
+		}
+		for key := range internalKeys {
+			if value := ctx.Get(key); value != nil {
+				c.Set(key, value)
+			}
+		}
+		if route := ctx.Route(); route != "" {
+			c.Set(routeTemplateKey, route)
+		}
+		next := iris.HandlerFunc(func(c *iris.Context) {
+			values := map[string]interface{}{}
+			c.VisitValues(func(key string, value interface{}) {
+				if _, ok := params[key]; !ok && key != routeTemplateKey {
+					values[key] = value
+					ctx.Set(key, value)
+				}
+			})
+			ctx.Set(irisValuesKey, values)
+			ctx.SetStatusCode(c.ResponseWriter.StatusCode())
+			ctx.Next()
+			// iris middlewares read the status after calling Next
+			c.SetStatusCode(ctx.StatusCode())
+		})
+		c.Middleware = append(handlers[:len(handlers):len(handlers)], next)
+		c.Do()
+		if c.IsStopped() {
+			ctx.StopExecution()
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package http_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alauda/bergamot/contexts"
+	"github.com/alauda/bergamot/http"
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/alauda/bergamot/log"
+
+	iris "gopkg.in/kataras/iris.v6"
+	"gopkg.in/kataras/iris.v6/adaptors/httprouter"
+)
+
+// muxTraceMiddleware appends its name to the X-Trace response header
+type muxTraceMiddleware string
+
+func (m muxTraceMiddleware) Serve(ctx mux.Context) {
+	ctx.ResponseWriter().Header().Add("X-Trace", string(m))
+	ctx.Next()
+}
+
+func TestIrisAdapter(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Method   string
+		Path     string
+		Expected string
+		Trace    string
+	}
+
+	table := []TestCase{
+		{"params and request ID", "GET", "/v1/users/10?q=a", "/v1/users/:id id=10 q=a request=abc", "global"},
+		{"unversioned", "GET", "/status", "/status", "global"},
+		{"party and use", "POST", "/v1/users/admin/10", "10", "global,admin,used"},
+	}
+
+	app := iris.New()
+	app.Adapt(httprouter.New())
+	app.Use(http.IrisMiddleware(http.NewRequestIDMiddleware()))
+	app.Use(http.IrisMiddleware(muxTraceMiddleware("global")))
+	router := http.NewIrisRouter(app.Party("/v1/users"))
+	router.Get("/:id", func(ctx mux.Context) {
+		c := http.Handler{}.GetContext(ctx, true)
+		ctx.WriteString(strings.Join([]string{
+			ctx.Route(),
+			"id=" + contexts.GetArgs(c)["id"],
+			"q=" + contexts.GetParams(c)["q"],
+			"request=" + contexts.GetRequestID(c),
+		}, " "))
+	})
+	http.NewIrisRouter(app.Party("/status")).Get("", func(ctx mux.Context) { ctx.WriteString(ctx.Route()) })
+	admin := router.Party("/admin", muxTraceMiddleware("admin").Serve)
+	admin.Use(muxTraceMiddleware("used"))
+	if route := admin.Post("/:id", func(ctx mux.Context) { ctx.WriteString(ctx.Param("id")) }); route.Path != "/v1/users/admin/:id" {
+		t.Errorf("unexpected route: %+v", route)
+	}
+	app.Boot()
+
+	for i, test := range table {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(test.Method, test.Path, nil)
+		request.Header.Set(http.RequestIDHeader, "abc")
+		app.ServeHTTP(recorder, request)
+		if body := recorder.Body.String(); body != test.Expected {
+			t.Errorf("%d - %s -- expected body %q got %q", i, test.Name, test.Expected, body)
+		}
+		if trace := strings.Join(recorder.Header()["X-Trace"], ","); trace != test.Trace {
+			t.Errorf("%d - %s -- expected trace %q got %q", i, test.Name, test.Trace, trace)
+		}
+	}
+}
+
+// irisTrace iris middleware appending its name to the X-Trace response header
+func irisTrace(name string) iris.HandlerFunc {
+	return func(ctx *iris.Context) {
+		ctx.ResponseWriter.Header().Add("X-Trace", name)
+		ctx.Next()
+	}
+}
+
+func TestFromIris(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Method   string
+		Path     string
+		Status   int
+		Expected string
+		Trace    string
+	}
+
+	table := []TestCase{
+		{"params and request ID", "GET", "/v1/users/10", 200, "/v1/users/:id id=10 request=abc", "iris,auth"},
+		{"party and endpoint middlewares", "POST", "/v1/users/admin/10", 201, "10 admin", "iris,auth,admin"},
+		{"direct route", "GET", "/status", 200, "ok", "iris"},
+		{"not found", "GET", "/missing", 404, "missing", ""},
+	}
+
+	var logged []int
+	server := http.NewServer(http.Config{
+		AddRequestID: true,
+		AddLog:       true,
+		LogFunc: http.FromIrisHandlerFunc(func(ctx *iris.Context) {
+			ctx.Next()
+			logged = append(logged, ctx.ResponseWriter.StatusCode())
+		}),
+		TreatNotFoundError: true,
+		NotFoundFunc: http.FromIrisHandlerFunc(func(ctx *iris.Context) {
+			ctx.SetStatusCode(404)
+			ctx.WriteString("missing")
+		}),
+	}, log.EmptyLogger{}).Init()
+	server.AddMiddleware(http.FromIrisMiddleware(irisTrace("iris")))
+	server.AddMiddleware(http.FromIrisMiddleware(iris.HandlerFunc(func(ctx *iris.Context) {
+		ctx.ResponseWriter.Header().Add("X-Trace", "auth")
+		ctx.Set("role", "admin")
+		ctx.Next()
+	})), "auth")
+	server.AddVersionEndpoint(1, "/users", http.FromIrisRouter(http.IrisAddRoutesFunc(func(router *iris.Router, server *http.Server) {
+		router.Get("/:id", func(ctx *iris.Context) {
+			ctx.WriteString(strings.Join([]string{
+				http.NewMuxContext(ctx).Route(),
+				"id=" + ctx.Param("id"),
+				"request=" + ctx.GetString(http.REQUESTID),
+			}, " "))
+		})
+		router.Party("/admin", irisTrace("admin")).Post("/:id", func(ctx *iris.Context) {
+			ctx.SetStatusCode(201)
+			ctx.WriteString(ctx.Param("id") + " " + ctx.GetString("role"))
+		})
+	})), http.WithMiddlewares("auth"))
+	http.FromIrisRouter(http.IrisAddRoutesFunc(func(router *iris.Router, server *http.Server) {
+		router.Get("/status", func(ctx *iris.Context) { ctx.WriteString("ok") })
+	})).AddRoutes(server.GetApp(), server)
+	server.Boot()
+
+	for i, test := range table {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(test.Method, test.Path, nil)
+		request.Header.Set(http.RequestIDHeader, "abc")
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != test.Status {
+			t.Errorf("%d - %s -- expected status %d got %d", i, test.Name, test.Status, recorder.Code)
+		}
+		if body := recorder.Body.String(); body != test.Expected {
+			t.Errorf("%d - %s -- expected body %q got %q", i, test.Name, test.Expected, body)
+		}
+		if trace := strings.Join(recorder.Header()["X-Trace"], ","); trace != test.Trace {
+			t.Errorf("%d - %s -- expected trace %q got %q", i, test.Name, test.Trace, trace)
+		}
+	}
+	if len(logged) != 3 || logged[0] != 200 || logged[1] != 201 {
+		t.Errorf("expected the log func to see the response status got: %v", logged)
+	}
+}
@@ -0,0 +1,34 @@
+package http
+
+import (
+	"strings"
+	"time"
+
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/alauda/bergamot/middleware"
+)
+
+// MetricsModule module used by MetricsMiddleware
+const MetricsModule = "http"
+
+// MetricsMiddleware generates latency, status and size metrics for every request
+// using the route template as action, e.g. /v1/users/:id
+type MetricsMiddleware struct {
+	metrics middleware.BaseMetrics
+}
+
+// NewMetricsMiddleware constructor function for MetricsMiddleware
+func NewMetricsMiddleware(metrics middleware.BaseMetrics) *MetricsMiddleware {
+	return &MetricsMiddleware{metrics: metrics}
+}
+
+// Serve calls the next handlers and generates the metrics
+func (m *MetricsMiddleware) Serve(ctx mux.Context) {
+	begin := time.Now()
+	ctx.Next()
+
+	action := GetRouteTemplate(ctx)
+	method := "method:" + strings.ToLower(ctx.Method())
+	m.metrics.GenerateStatusMetrics(begin, MetricsModule, action, ctx.StatusCode(), method)
+	m.metrics.GenerateSizeMetrics(MetricsModule, action, ctx.Request().ContentLength, GetResponseSize(ctx), method)
+}
@@ -0,0 +1,55 @@
+package http_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alauda/bergamot/http"
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/alauda/bergamot/log"
+	"github.com/alauda/bergamot/metrics"
+	"github.com/alauda/bergamot/middleware"
+)
+
+type recordMetrics struct {
+	metrics.ClosedClient
+	counts     map[string][]string
+	histograms map[string]float64
+}
+
+func (r *recordMetrics) Count(name string, value int64, tags []string, rate float64) error {
+	r.counts[name] = tags
+	return nil
+}
+
+func (r *recordMetrics) Histogram(name string, value float64, tags []string, rate float64) error {
+	r.histograms[name] = value
+	return nil
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	client := &recordMetrics{counts: map[string][]string{}, histograms: map[string]float64{}}
+	server := http.NewServer(http.Config{}, log.EmptyLogger{}).Init()
+	server.AddMiddleware(http.NewMetricsMiddleware(middleware.NewMetrics("test", 1, client)))
+	server.AddVersionEndpointFunc(1, "/users", func(router mux.Router, server *http.Server) {
+		router.Post("/:id", func(ctx mux.Context) {
+			ctx.SetStatusCode(201)
+			ctx.WriteString("created")
+		})
+	})
+	server.Boot()
+
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/users/123", strings.NewReader("{}")))
+
+	tags, ok := client.counts["comp.test.requests.http.201"]
+	if !ok {
+		t.Fatalf("expected status count metric, got: %v", client.counts)
+	}
+	if len(tags) != 2 || tags[0] != "action:/v1/users/:id" || tags[1] != "method:post" {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+	if client.histograms["comp.test.requests.http.request_size"] != 2 || client.histograms["comp.test.requests.http.response_size"] != 7 {
+		t.Errorf("unexpected sizes: %v", client.histograms)
+	}
+}
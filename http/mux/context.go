@@ -0,0 +1,274 @@
+package mux
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+
+	"github.com/alauda/bergamot/utils"
+
+	"github.com/iris-contrib/formBinder"
+)
+
+// maxFormMemory bytes of multipart forms kept in memory
+const maxFormMemory = 32 << 20
+
+// Context request context passed to handlers
+// implemented by the net/http Mux and by the iris adapter of the http package
+type Context interface {
+	// Request returns the HTTP request
+	Request() *http.Request
+	// ResponseWriter returns the writer of the response
+	ResponseWriter() http.ResponseWriter
+
+	// Method returns the request method
+	Method() string
+	// Path returns the request path
+	Path() string
+	// Route returns the registered route template like /v1/users/:id
+	Route() string
+	// Param returns a path parameter like id for /v1/users/:id
+	Param(name string) string
+	// Params returns all the path parameters
+	Params() map[string]string
+	// URLParam returns a query parameter
+	URLParam(name string) string
+	// URLParams returns all the query parameters
+	URLParams() map[string]string
+	// RequestHeader returns a request header
+	RequestHeader(name string) string
+	// RemoteAddr returns the client IP
+	RemoteAddr() string
+	// ReadJSON decodes the JSON body into v
+	ReadJSON(v interface{}) error
+	// ReadForm decodes the form body and the query parameters into v
+	ReadForm(v interface{}) error
+
+	// SetHeader sets a response header replacing any previous value
+	SetHeader(name, value string)
+	// SetStatusCode sets the response status code
+	SetStatusCode(status int)
+	// StatusCode returns the response status code
+	StatusCode() int
+	// Write writes the response body
+	Write(data []byte) (int, error)
+	// WriteString writes a string as the response body
+	WriteString(s string) (int, error)
+	// JSON writes v as JSON with the status code
+	JSON(status int, v interface{}) error
+	// Flush sends the buffered response to the client
+	Flush()
+
+	// Set stores a value for the next handlers
+	Set(key string, value interface{})
+	// Get returns a value stored using Set
+	Get(key string) interface{}
+
+	// Next calls the next handler
+	Next()
+	// StopExecution stops calling the next handlers
+	StopExecution()
+	// IsStopped returns true if StopExecution was called
+	IsStopped() bool
+}
+
+// HandlerFunc function that handles requests
+type HandlerFunc func(ctx Context)
+
+// Serve calls the function, satisfies the Middleware interface
+func (f HandlerFunc) Serve(ctx Context) {
+	f(ctx)
+}
+
+// Middleware handler that runs before the route handlers
+// should call ctx.Next to continue
+type Middleware interface {
+	Serve(ctx Context)
+}
+
+// Chain returns a handler running the handlers before the next handlers of the route
+// each handler should call ctx.Next to continue
+func Chain(handlers ...HandlerFunc) HandlerFunc {
+	return func(ctx Context) {
+		(&chainContext{Context: ctx, handlers: handlers, index: -1}).Next()
+	}
+}
+
+// chainContext runs its handlers before calling Next on the wrapped context
+type chainContext struct {
+	Context
+	handlers []HandlerFunc
+	index    int
+}
+
+func (c *chainContext) Next() {
+	if c.IsStopped() {
+		return
+	}
+	c.index++
+	switch {
+	case c.index < len(c.handlers):
+		c.handlers[c.index](c)
+	case c.index == len(c.handlers):
+		c.Context.Next()
+	}
+}
+
+// stopped index used when the execution is stopped
+const stopped = 1 << 30
+
+// requestContext net/http implementation of Context
+type requestContext struct {
+	request  *http.Request
+	writer   *responseWriter
+	route    string
+	params   map[string]string
+	values   map[string]interface{}
+	handlers []HandlerFunc
+	index    int
+	// trusted proxies allowed to set the client IP headers
+	trusted []*net.IPNet
+}
+
+func newContext(w http.ResponseWriter, r *http.Request, route string, params map[string]string, handlers []HandlerFunc, trusted []*net.IPNet) *requestContext {
+	return &requestContext{
+		request:  r,
+		writer:   &responseWriter{ResponseWriter: w, status: http.StatusOK},
+		route:    route,
+		params:   params,
+		handlers: handlers,
+		index:    -1,
+		trusted:  trusted,
+	}
+}
+
+func (c *requestContext) Request() *http.Request              { return c.request }
+func (c *requestContext) ResponseWriter() http.ResponseWriter { return c.writer }
+func (c *requestContext) Method() string                      { return c.request.Method }
+func (c *requestContext) Path() string                        { return c.request.URL.Path }
+func (c *requestContext) Route() string                       { return c.route }
+func (c *requestContext) Param(name string) string            { return c.params[name] }
+func (c *requestContext) RequestHeader(name string) string    { return c.request.Header.Get(name) }
+func (c *requestContext) URLParam(name string) string         { return c.request.URL.Query().Get(name) }
+func (c *requestContext) SetHeader(name, value string)        { c.writer.Header().Set(name, value) }
+func (c *requestContext) SetStatusCode(status int)            { c.writer.WriteHeader(status) }
+func (c *requestContext) StatusCode() int                     { return c.writer.status }
+func (c *requestContext) Write(data []byte) (int, error)      { return c.writer.Write(data) }
+func (c *requestContext) WriteString(s string) (int, error)   { return c.writer.Write([]byte(s)) }
+func (c *requestContext) Flush()                              { c.writer.Flush() }
+func (c *requestContext) StopExecution()                      { c.index = stopped }
+func (c *requestContext) IsStopped() bool                     { return c.index >= stopped }
+
+func (c *requestContext) Params() map[string]string {
+	params := make(map[string]string, len(c.params))
+	for k, v := range c.params {
+		params[k] = v
+	}
+	return params
+}
+
+func (c *requestContext) URLParams() map[string]string {
+	values := c.request.URL.Query()
+	params := make(map[string]string, len(values))
+	for k := range values {
+		params[k] = values.Get(k)
+	}
+	return params
+}
+
+// RemoteAddr returns the client IP from the X-Forwarded-For header for requests
+// from the trusted proxies of the Mux, otherwise the IP of the connection
+func (c *requestContext) RemoteAddr() string {
+	return utils.ClientIP(c.request, c.trusted)
+}
+
+func (c *requestContext) ReadJSON(v interface{}) error {
+	return json.NewDecoder(c.request.Body).Decode(v)
+}
+
+func (c *requestContext) ReadForm(v interface{}) error {
+	var err error
+	if mediaType, _, _ := mime.ParseMediaType(c.request.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		err = c.request.ParseMultipartForm(maxFormMemory)
+	} else {
+		err = c.request.ParseForm()
+	}
+	if err != nil {
+		return err
+	}
+	return formBinder.Decode(c.request.Form, v)
+}
+
+func (c *requestContext) JSON(status int, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.writer.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	c.writer.WriteHeader(status)
+	_, err = c.writer.Write(data)
+	return err
+}
+
+func (c *requestContext) Set(key string, value interface{}) {
+	if c.values == nil {
+		c.values = map[string]interface{}{}
+	}
+	c.values[key] = value
+}
+
+func (c *requestContext) Get(key string) interface{} {
+	return c.values[key]
+}
+
+func (c *requestContext) Next() {
+	c.index++
+	if c.index < len(c.handlers) {
+		c.handlers[c.index](c)
+	}
+}
+
+// responseWriter keeps the status code until the body is written
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	written bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.written {
+		w.status = status
+	}
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.writeHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseWriter) writeHeader() {
+	if !w.written {
+		w.written = true
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+// Flush sends the status code and flushes the underlying writer if supported
+func (w *responseWriter) Flush() {
+	w.writeHeader()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack hijacks the underlying writer if supported
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		w.written = true
+		return hijacker.Hijack()
+	}
+	return nil, nil, errors.New("hijack is not supported by this ResponseWriter")
+}
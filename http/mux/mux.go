@@ -0,0 +1,259 @@
+// Package mux bergamot request context and router
+// with a lightweight net/http implementation
+package mux
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AnyMethods methods registered by Any
+var AnyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+	http.MethodHead, http.MethodOptions, http.MethodConnect, http.MethodTrace,
+}
+
+// Router registers routes and middlewares
+type Router interface {
+	// Handle registers the handlers for a method and a path relative to the router
+	// paths can have parameters like /users/:id and a catch-all like /files/*path.
+	// Returns the registered route
+	Handle(method, path string, handlers ...HandlerFunc) RouteInfo
+	Get(path string, handlers ...HandlerFunc) RouteInfo
+	Post(path string, handlers ...HandlerFunc) RouteInfo
+	Put(path string, handlers ...HandlerFunc) RouteInfo
+	Patch(path string, handlers ...HandlerFunc) RouteInfo
+	Delete(path string, handlers ...HandlerFunc) RouteInfo
+	// Any registers the handlers for all the AnyMethods
+	Any(path string, handlers ...HandlerFunc)
+	// Party returns a router for a path prefix
+	// handlers run before the handlers of all its routes
+	Party(prefix string, handlers ...HandlerFunc) Router
+	// Use adds middlewares to the routes registered afterwards
+	Use(mws ...Middleware)
+}
+
+// AddRoutesFunc function that registers routes in a router
+type AddRoutesFunc func(router Router)
+
+// RouteInfo registered route
+type RouteInfo struct {
+	Method string
+	// Path route template like /v1/users/:id
+	Path string
+}
+
+type route struct {
+	method   string
+	path     string
+	segments []string
+	handlers []HandlerFunc
+}
+
+// Mux net/http Router implementation
+type Mux struct {
+	group
+	lock   sync.RWMutex
+	routes map[string][]*route
+	// NotFound handler for unknown paths, defaults to a 404 status
+	NotFound HandlerFunc
+	// MethodNotAllowed handler for known paths with another method, defaults to a 405 status
+	MethodNotAllowed HandlerFunc
+	// TrustedProxies proxies allowed to set the client IP
+	// using the X-Forwarded-For header
+	TrustedProxies []*net.IPNet
+}
+
+// New constructor function for Mux
+func New() *Mux {
+	m := &Mux{routes: map[string][]*route{}}
+	m.group = group{mux: m}
+	return m
+}
+
+// group router for a path prefix
+type group struct {
+	mux      *Mux
+	prefix   string
+	handlers []HandlerFunc
+}
+
+func (g *group) Handle(method, path string, handlers ...HandlerFunc) RouteInfo {
+	chain := make([]HandlerFunc, 0, len(g.handlers)+len(handlers))
+	chain = append(append(chain, g.handlers...), handlers...)
+	return g.mux.add(strings.ToUpper(method), joinPath(g.prefix, path), chain)
+}
+
+func (g *group) Get(path string, handlers ...HandlerFunc) RouteInfo {
+	return g.Handle(http.MethodGet, path, handlers...)
+}
+func (g *group) Post(path string, handlers ...HandlerFunc) RouteInfo {
+	return g.Handle(http.MethodPost, path, handlers...)
+}
+func (g *group) Put(path string, handlers ...HandlerFunc) RouteInfo {
+	return g.Handle(http.MethodPut, path, handlers...)
+}
+func (g *group) Patch(path string, handlers ...HandlerFunc) RouteInfo {
+	return g.Handle(http.MethodPatch, path, handlers...)
+}
+func (g *group) Delete(path string, handlers ...HandlerFunc) RouteInfo {
+	return g.Handle(http.MethodDelete, path, handlers...)
+}
+
+func (g *group) Any(path string, handlers ...HandlerFunc) {
+	for _, method := range AnyMethods {
+		g.Handle(method, path, handlers...)
+	}
+}
+
+func (g *group) Party(prefix string, handlers ...HandlerFunc) Router {
+	chain := make([]HandlerFunc, 0, len(g.handlers)+len(handlers))
+	return &group{
+		mux:      g.mux,
+		prefix:   joinPath(g.prefix, prefix),
+		handlers: append(append(chain, g.handlers...), handlers...),
+	}
+}
+
+func (g *group) Use(mws ...Middleware) {
+	for _, mw := range mws {
+		g.handlers = append(g.handlers, mw.Serve)
+	}
+}
+
+func (m *Mux) add(method, path string, handlers []HandlerFunc) RouteInfo {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.routes[method] = append(m.routes[method], &route{
+		method:   method,
+		path:     path,
+		segments: SplitPath(path),
+		handlers: handlers,
+	})
+	return RouteInfo{Method: method, Path: path}
+}
+
+// Routes returns all the registered routes sorted by path and method
+func (m *Mux) Routes() []RouteInfo {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	var routes []RouteInfo
+	for _, collection := range m.routes {
+		for _, r := range collection {
+			routes = append(routes, RouteInfo{Method: r.method, Path: r.path})
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// ServeHTTP finds the route of the request and calls its handlers
+func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := SplitPath(r.URL.Path)
+	m.lock.RLock()
+	matched, params := match(m.routes[r.Method], segments)
+	var allowed []string
+	if matched == nil {
+		for method, collection := range m.routes {
+			if method != r.Method {
+				if found, _ := match(collection, segments); found != nil {
+					allowed = append(allowed, method)
+				}
+			}
+		}
+	}
+	m.lock.RUnlock()
+
+	if matched != nil {
+		ctx := newContext(w, r, matched.path, params, matched.handlers, m.TrustedProxies)
+		ctx.Next()
+		ctx.writer.writeHeader()
+		return
+	}
+	handler, status := m.NotFound, http.StatusNotFound
+	if len(allowed) > 0 {
+		sort.Strings(allowed)
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		handler, status = m.MethodNotAllowed, http.StatusMethodNotAllowed
+	}
+	ctx := newContext(w, r, "", map[string]string{}, nil, m.TrustedProxies)
+	if handler != nil {
+		handler(ctx)
+	} else {
+		ctx.SetStatusCode(status)
+	}
+	ctx.writer.writeHeader()
+}
+
+// match returns the route that best matches the path segments
+// preferring static segments over parameters
+func match(routes []*route, segments []string) (*route, map[string]string) {
+	var (
+		best      *route
+		bestScore = -1
+	)
+	for _, r := range routes {
+		if score := MatchSegments(r.segments, segments); score > bestScore {
+			best, bestScore = r, score
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	params := map[string]string{}
+	for i, part := range best.segments {
+		switch {
+		case strings.HasPrefix(part, "*"):
+			params[part[1:]] = strings.Join(segments[i:], "/")
+		case strings.HasPrefix(part, ":"):
+			params[part[1:]] = segments[i]
+		}
+	}
+	return best, params
+}
+
+// MatchSegments returns the number of static segments of a route template matched
+// or -1 if the template does not match the segments of a path
+func MatchSegments(template, segments []string) int {
+	score := 0
+	for i, part := range template {
+		if strings.HasPrefix(part, "*") {
+			return score
+		}
+		if i >= len(segments) {
+			return -1
+		}
+		if strings.HasPrefix(part, ":") {
+			continue
+		}
+		if part != segments[i] {
+			return -1
+		}
+		score++
+	}
+	if len(template) != len(segments) {
+		return -1
+	}
+	return score
+}
+
+// SplitPath returns the segments of a path without empty leading and trailing segments
+func SplitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return []string{}
+	}
+	return strings.Split(path, "/")
+}
+
+func joinPath(prefix, path string) string {
+	return "/" + strings.Trim(strings.Trim(prefix, "/")+"/"+strings.Trim(path, "/"), "/")
+}
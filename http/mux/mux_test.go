@@ -0,0 +1,132 @@
+package mux_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alauda/bergamot/http/mux"
+)
+
+// traceMiddleware appends its name to the X-Trace response header
+type traceMiddleware string
+
+func (m traceMiddleware) Serve(ctx mux.Context) {
+	ctx.ResponseWriter().Header().Add("X-Trace", string(m))
+	ctx.Next()
+}
+
+func TestMux(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Method   string
+		Path     string
+		Status   int
+		Body     string
+		Trace    string
+		Expected http.Header
+	}
+
+	table := []TestCase{
+		{"static route", "GET", "/v1/users/me", 200, "me /v1/users/me", "party", nil},
+		{"parameter", "GET", "/v1/users/10", 200, "id=10 /v1/users/:id", "party", nil},
+		{"middleware after", "POST", "/v1/users/10", 201, `{"id":"10"}`, "party,used", nil},
+		{"catch-all", "GET", "/files/a/b.txt", 200, "a/b.txt", "", nil},
+		{"stopped", "DELETE", "/v1/users/10", 403, "", "party,used", nil},
+		{"chain", "GET", "/chain", 200, "chained", "first,second,after", http.Header{"X-Value": {"replaced"}}},
+		{"stopped chain", "GET", "/chain/stopped", 403, "", "first", nil},
+		{"not found", "GET", "/v2/users", 404, "", "", nil},
+		{"method not allowed", "PUT", "/v1/users/10", 405, "", "", http.Header{"Allow": {"DELETE, GET, POST"}}},
+	}
+
+	m := mux.New()
+	m.Get("/files/*path", func(ctx mux.Context) {
+		ctx.Write([]byte(ctx.Param("path")))
+	})
+	m.Get("/chain", mux.Chain(traceMiddleware("first").Serve, traceMiddleware("second").Serve), traceMiddleware("after").Serve, func(ctx mux.Context) {
+		ctx.SetHeader("X-Value", "set")
+		ctx.SetHeader("X-Value", "replaced")
+		ctx.Write([]byte("chained"))
+	})
+	m.Get("/chain/stopped", mux.Chain(traceMiddleware("first").Serve, func(ctx mux.Context) {
+		ctx.SetStatusCode(http.StatusForbidden)
+		ctx.StopExecution()
+	}), traceMiddleware("after").Serve)
+	users := m.Party("/v1/users", traceMiddleware("party").Serve)
+	users.Get("/:id", func(ctx mux.Context) {
+		ctx.Write([]byte("id=" + ctx.Param("id") + " " + ctx.Route()))
+	})
+	users.Get("/me", func(ctx mux.Context) {
+		ctx.Write([]byte("me " + ctx.Route()))
+	})
+	users.Use(traceMiddleware("used"))
+	users.Post("/:id", func(ctx mux.Context) {
+		ctx.JSON(http.StatusCreated, ctx.Params())
+	})
+	users.Delete("/:id", func(ctx mux.Context) {
+		ctx.SetStatusCode(http.StatusForbidden)
+		ctx.StopExecution()
+	}, func(ctx mux.Context) {
+		ctx.Write([]byte("not stopped"))
+	})
+
+	for i, test := range table {
+		recorder := httptest.NewRecorder()
+		m.ServeHTTP(recorder, httptest.NewRequest(test.Method, test.Path, nil))
+		if recorder.Code != test.Status {
+			t.Errorf("%d - %s -- expected status %d got %d", i, test.Name, test.Status, recorder.Code)
+		}
+		if body := strings.TrimSpace(recorder.Body.String()); body != test.Body {
+			t.Errorf("%d - %s -- expected body %q got %q", i, test.Name, test.Body, body)
+		}
+		if trace := strings.Join(recorder.Header()["X-Trace"], ","); trace != test.Trace {
+			t.Errorf("%d - %s -- expected trace %q got %q", i, test.Name, test.Trace, trace)
+		}
+		for k := range test.Expected {
+			if recorder.Header().Get(k) != test.Expected.Get(k) {
+				t.Errorf("%d - %s -- expected header %s: %s got %s", i, test.Name, k, test.Expected.Get(k), recorder.Header().Get(k))
+			}
+		}
+	}
+
+	if routes := m.Routes(); len(routes) != 7 || routes[0].Path != "/chain" || routes[3].Method != "DELETE" {
+		t.Errorf("unexpected routes: %+v", routes)
+	}
+}
+
+func TestRemoteAddr(t *testing.T) {
+	type TestCase struct {
+		Name       string
+		RemoteAddr string
+		Headers    map[string]string
+		Expected   string
+	}
+
+	table := []TestCase{
+		{"connection", "10.0.0.1:1234", nil, "10.0.0.1"},
+		{"real IP ignored", "10.0.0.1:1234", map[string]string{"X-Real-Ip": "1.1.1.1", "X-Forwarded-For": "2.2.2.2"}, "2.2.2.2"},
+		{"forwarded", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "2.2.2.2, 10.0.0.1"}, "2.2.2.2"},
+		{"spoofed forwarded", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "6.6.6.6, 2.2.2.2"}, "2.2.2.2"},
+		{"untrusted proxy", "192.168.0.1:1234", map[string]string{"X-Real-Ip": "1.1.1.1", "X-Forwarded-For": "2.2.2.2"}, "192.168.0.1"},
+	}
+
+	_, trusted, _ := net.ParseCIDR("10.0.0.0/8")
+	m := mux.New()
+	m.TrustedProxies = []*net.IPNet{trusted}
+	m.Get("/ip", func(ctx mux.Context) { ctx.WriteString(ctx.RemoteAddr()) })
+
+	for i, test := range table {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest("GET", "/ip", nil)
+		request.RemoteAddr = test.RemoteAddr
+		for k, v := range test.Headers {
+			request.Header.Set(k, v)
+		}
+		m.ServeHTTP(recorder, request)
+		if body := recorder.Body.String(); body != test.Expected {
+			t.Errorf("%d - %s -- expected %q got %q", i, test.Name, test.Expected, body)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alauda/bergamot/errors"
+	"github.com/alauda/bergamot/http/mux"
+)
+
+// OpenAPIPath path of the OpenAPI document inside each version
+const OpenAPIPath = "/openapi.json"
+
+// undocumentedMethods methods left out of the OpenAPI document
+// registered for every route added using Any
+var undocumentedMethods = map[string]struct{}{
+	http.MethodOptions: {},
+	http.MethodHead:    {},
+	http.MethodConnect: {},
+	http.MethodTrace:   {},
+}
+
+// RouteDoc optional documentation of a route used in the OpenAPI document
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	// Request value of the request body type, e.g. CreateUser{}
+	Request interface{}
+	// Response value of the response body type, e.g. []User{}
+	Response interface{}
+	// Status status code of successful responses, defaults to 200
+	Status int
+	// Errors codes that can be returned, documented using their ErrorMessageList status
+	Errors []errors.Code
+}
+
+// Route registered route
+type Route struct {
+	Method string
+	// Path route template like /v1/users/:id
+	Path string
+	// Version API version of the route, 0 for routes outside versions
+	Version int
+	// Doc documentation of the route, nil if not documented
+	Doc *RouteDoc
+}
+
+// Document adds the documentation of a route
+// returns the route to be used when registering routes, e.g.
+//
+//	server.Document(router.Get("/:id", h.Get), http.RouteDoc{Summary: "Get a user"})
+func (h *Server) Document(route mux.RouteInfo, doc RouteDoc) mux.RouteInfo {
+	if h.docs == nil {
+		h.docs = map[string]RouteDoc{}
+	}
+	h.docs[route.Method+" "+route.Path] = doc
+	return route
+}
+
+// Routes returns all the registered routes sorted by path and method
+func (h *Server) Routes() []Route {
+	infos := h.mux.Routes()
+	routes := make([]Route, len(infos))
+	for i, route := range infos {
+		routes[i] = Route{
+			Method:  route.Method,
+			Path:    route.Path,
+			Version: getRouteVersion(route.Path),
+		}
+		if doc, ok := h.docs[route.Method+" "+route.Path]; ok {
+			routes[i].Doc = &doc
+		}
+	}
+	return routes
+}
+
+// getRouteVersion returns n for paths starting with /v{n}/
+func getRouteVersion(path string) int {
+	segments := mux.SplitPath(path)
+	if len(segments) == 0 || !strings.HasPrefix(segments[0], "v") {
+		return 0
+	}
+	version, _ := strconv.Atoi(segments[0][1:])
+	return version
+}
+
+// OpenAPI generates the OpenAPI 3 document of the routes of a version
+func (h *Server) OpenAPI(version int) map[string]interface{} {
+	generator := newOpenAPIGenerator()
+	paths := map[string]map[string]interface{}{}
+	for _, route := range h.Routes() {
+		if _, ok := undocumentedMethods[route.Method]; ok {
+			continue
+		}
+		if route.Version != version || route.Path == fmt.Sprintf("/v%d%s", version, OpenAPIPath) {
+			continue
+		}
+		path := getOpenAPIPath(route.Path)
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+		paths[path][strings.ToLower(route.Method)] = generator.operation(route)
+	}
+	title := h.config.Component
+	if title == "" {
+		title = "API"
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.0",
+		"info": map[string]interface{}{
+			"title":   title,
+			"version": fmt.Sprintf("v%d", version),
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": generator.schemas,
+		},
+	}
+}
+
+// serveOpenAPI returns a handler serving the OpenAPI document of a version
+func (h *Server) serveOpenAPI(version int) mux.HandlerFunc {
+	return func(ctx mux.Context) {
+		ctx.JSON(http.StatusOK, h.OpenAPI(version))
+	}
+}
+
+// getOpenAPIPath converts a route template like /v1/users/:id to /v1/users/{id}
+func getOpenAPIPath(path string) string {
+	segments := mux.SplitPath(path)
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+type openAPIGenerator struct {
+	schemas map[string]interface{}
+	// names component name of each type
+	names map[reflect.Type]string
+	// types type of each component name
+	types map[string]reflect.Type
+}
+
+func newOpenAPIGenerator() *openAPIGenerator {
+	return &openAPIGenerator{
+		schemas: map[string]interface{}{},
+		// the error envelopes keep their short names
+		names: map[reflect.Type]string{errorItemType: "Error", errorsType: "Errors"},
+		types: map[string]reflect.Type{"Error": errorItemType, "Errors": errorsType},
+	}
+}
+
+func (g *openAPIGenerator) operation(route Route) map[string]interface{} {
+	doc := RouteDoc{}
+	if route.Doc != nil {
+		doc = *route.Doc
+	}
+	operation := map[string]interface{}{}
+	if doc.Summary != "" {
+		operation["summary"] = doc.Summary
+	}
+	if doc.Description != "" {
+		operation["description"] = doc.Description
+	}
+	if len(doc.Tags) > 0 {
+		operation["tags"] = doc.Tags
+	}
+	var parameters []interface{}
+	for _, segment := range mux.SplitPath(route.Path) {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			parameters = append(parameters, map[string]interface{}{
+				"name":     segment[1:],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+	if doc.Request != nil {
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  g.content(doc.Request),
+		}
+	}
+
+	status := doc.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	if doc.Response != nil {
+		success["content"] = g.content(doc.Response)
+	}
+	responses := map[string]interface{}{strconv.Itoa(status): success}
+	for status, messages := range getErrorStatuses(doc.Errors) {
+		responses[strconv.Itoa(status)] = map[string]interface{}{
+			"description": strings.Join(messages, " "),
+			"content":     g.content(AlaudaError{}),
+		}
+	}
+	operation["responses"] = responses
+	return operation
+}
+
+// getErrorStatuses groups the error messages by status code
+func getErrorStatuses(codes []errors.Code) map[int][]string {
+	statuses := map[int][]string{}
+	for _, code := range codes {
+		status, message := http.StatusInternalServerError, string(code)
+		if value, ok := errors.ErrorMessageList[code]; ok {
+			status = value.StatusCode
+			message = fmt.Sprintf("%s: %s", code, value.Message)
+		}
+		statuses[status] = append(statuses[status], message)
+	}
+	return statuses
+}
+
+func (g *openAPIGenerator) content(v interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		ContentTypeJSON: map[string]interface{}{"schema": g.schema(reflect.TypeOf(v))},
+	}
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	errorType     = reflect.TypeOf((*error)(nil)).Elem()
+	errorItemType = reflect.TypeOf(errors.AlaudaError{})
+	errorsType    = reflect.TypeOf(AlaudaError{})
+	// componentChars characters not allowed in component names
+	componentChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// schema returns the JSON schema of a type
+// named structs are added to the components and referenced
+func (g *openAPIGenerator) schema(t reflect.Type) map[string]interface{} {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return map[string]interface{}{}
+	}
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == errorType:
+		return g.ref(errorItemType)
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return g.ref(t)
+	}
+	return map[string]interface{}{}
+}
+
+// ref adds the schema of a struct to the components and returns its reference
+func (g *openAPIGenerator) ref(t reflect.Type) map[string]interface{} {
+	name := g.componentName(t)
+	if _, ok := g.schemas[name]; !ok {
+		// placeholder for recursive types
+		g.schemas[name] = nil
+		g.schemas[name] = g.object(t)
+	}
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// componentName returns the name of a struct in the components
+// types with the same name as another type are qualified with their package path
+func (g *openAPIGenerator) componentName(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := componentChars.ReplaceAllString(t.Name(), "_")
+	if _, taken := g.types[name]; taken {
+		name = componentChars.ReplaceAllString(strings.Replace(t.PkgPath(), "/", ".", -1)+"."+t.Name(), "_")
+		for i, qualified := 2, name; ; i++ {
+			if _, taken = g.types[name]; !taken {
+				break
+			}
+			name = fmt.Sprintf("%s%d", qualified, i)
+		}
+	}
+	g.names[t] = name
+	g.types[name] = t
+	return name
+}
+
+// object returns the schema of a struct using the json tags
+// fields with the required validation rule are required
+func (g *openAPIGenerator) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	g.fields(t, properties, &required)
+	object := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		object["required"] = required
+	}
+	return object
+}
+
+func (g *openAPIGenerator) fields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		name := tag[0]
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			g.fields(field.Type, properties, required)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema := g.schema(field.Type)
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			switch {
+			case rule == "required":
+				*required = append(*required, name)
+			case strings.HasPrefix(rule, "enum="):
+				schema["enum"] = enumValues(schema, strings.Split(strings.TrimPrefix(rule, "enum="), "|"))
+			}
+		}
+		properties[name] = schema
+	}
+}
+
+// enumValues converts the values of an enum rule to the type of the schema
+// values that can not be converted are kept as strings
+func enumValues(schema map[string]interface{}, values []string) []interface{} {
+	enum := make([]interface{}, len(values))
+	for i, value := range values {
+		enum[i] = value
+		switch schema["type"] {
+		case "integer":
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				enum[i] = n
+			}
+		case "number":
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				enum[i] = f
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(value); err == nil {
+				enum[i] = b
+			}
+		}
+	}
+	return enum
+}
+
+// MarshalOpenAPI returns the OpenAPI document of a version as indented JSON
+func (h *Server) MarshalOpenAPI(version int) ([]byte, error) {
+	return json.MarshalIndent(h.OpenAPI(version), "", "  ")
+}
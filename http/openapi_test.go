@@ -0,0 +1,137 @@
+package http_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/alauda/bergamot/errors"
+	"github.com/alauda/bergamot/http"
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/alauda/bergamot/log"
+)
+
+type user struct {
+	Name  string  `json:"name" validate:"required"`
+	Role  string  `json:"role,omitempty" validate:"enum=admin|viewer"`
+	Level int     `json:"level,omitempty" validate:"enum=1|2"`
+	Quota float64 `json:"quota,omitempty" validate:"enum=0.5|1"`
+}
+
+// Error user type with the same name as the error schema
+type Error struct {
+	Reason string `json:"reason"`
+}
+
+// otherUser returns a type with the same name and package as user
+func otherUser() interface{} {
+	type user struct {
+		ID int `json:"id"`
+	}
+	return user{}
+}
+
+func TestOpenAPI(t *testing.T) {
+	server := http.NewServer(http.Config{Component: "users", AddOpenAPI: true}, log.EmptyLogger{}).Init()
+	server.AddVersionEndpointFunc(1, "/users", func(router mux.Router, server *http.Server) {
+		server.Document(router.Get("/:id", func(ctx mux.Context) {}), http.RouteDoc{
+			Summary:  "Get a user",
+			Response: user{},
+			Errors:   []errors.Code{errors.ErrorCodeResourceNotFound, errors.ErrorCodePermissionDenied},
+		})
+		server.Document(router.Post("", func(ctx mux.Context) {}), http.RouteDoc{
+			Request:  user{},
+			Response: otherUser(),
+			Status:   201,
+		})
+		server.Document(router.Delete("/:id", func(ctx mux.Context) {}), http.RouteDoc{
+			Response: Error{},
+			Errors:   []errors.Code{errors.ErrorCodeResourceNotFound},
+		})
+	})
+	server.AddVersionEndpointFunc(2, "/users", func(router mux.Router, server *http.Server) {
+		router.Get("", func(ctx mux.Context) {})
+	})
+	server.Boot()
+
+	routes := server.Routes()
+	if len(routes) != 6 || routes[2].Path != "/v1/users/:id" || routes[2].Version != 1 || routes[2].Doc == nil {
+		t.Fatalf("unexpected routes: %+v", routes)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest("GET", "/v1/openapi.json", nil))
+	var document struct {
+		Info  map[string]string
+		Paths map[string]map[string]struct {
+			Summary    string
+			Parameters []map[string]interface{}
+			Responses  map[string]interface{}
+		}
+		Components struct {
+			Schemas map[string]struct {
+				Required   []string
+				Properties map[string]map[string]interface{}
+			}
+		}
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &document); err != nil {
+		t.Fatalf("unexpected error: %v: %s", err, recorder.Body.String())
+	}
+	if document.Info["title"] != "users" || document.Info["version"] != "v1" || len(document.Paths) != 2 {
+		t.Errorf("unexpected document: %s", recorder.Body.String())
+	}
+	get := document.Paths["/v1/users/{id}"]["get"]
+	if get.Summary != "Get a user" || len(get.Parameters) != 1 || get.Parameters[0]["name"] != "id" {
+		t.Errorf("unexpected operation: %+v", get)
+	}
+	for _, status := range []string{"200", "403", "404"} {
+		if _, ok := get.Responses[status]; !ok {
+			t.Errorf("expected %s response: %+v", status, get.Responses)
+		}
+	}
+	if _, ok := document.Paths["/v1/users"]["post"].Responses["201"]; !ok {
+		t.Errorf("expected 201 response: %+v", document.Paths["/v1/users"])
+	}
+	schema := document.Components.Schemas["user"]
+	if len(schema.Required) != 1 || schema.Required[0] != "name" || schema.Properties["role"]["enum"] == nil {
+		t.Errorf("unexpected user schema: %+v", schema)
+	}
+	for property, expected := range map[string][]interface{}{
+		"role":  {"admin", "viewer"},
+		"level": {1.0, 2.0},
+		"quota": {0.5, 1.0},
+	} {
+		if enum, _ := schema.Properties[property]["enum"].([]interface{}); !reflect.DeepEqual(enum, expected) {
+			t.Errorf("expected %s enum %v got %#v", property, expected, schema.Properties[property]["enum"])
+		}
+	}
+	for _, name := range []string{"Errors", "Error", "github.com.alauda.bergamot.http_test.user", "github.com.alauda.bergamot.http_test.Error"} {
+		if _, ok := document.Components.Schemas[name]; !ok {
+			t.Errorf("expected %s schema: %+v", name, document.Components.Schemas)
+		}
+	}
+	if _, ok := document.Components.Schemas["Error"].Properties["reason"]; ok {
+		t.Errorf("user type replaced the error schema: %+v", document.Components.Schemas["Error"])
+	}
+}
+
+func TestOpenAPIAnyRoutes(t *testing.T) {
+	server := http.NewServer(http.Config{AddOpenAPI: true}, log.EmptyLogger{}).Init()
+	server.AddVersionEndpointFunc(1, "/proxy", func(router mux.Router, server *http.Server) {
+		router.Any("", func(ctx mux.Context) {})
+	})
+	server.Boot()
+
+	paths, _ := server.OpenAPI(1)["paths"].(map[string]map[string]interface{})
+	operations := paths["/v1/proxy"]
+	if len(operations) != 5 {
+		t.Errorf("expected get, post, put, patch and delete operations got: %v", operations)
+	}
+	for _, method := range []string{"options", "head", "connect", "trace"} {
+		if _, ok := operations[method]; ok {
+			t.Errorf("unexpected %s operation: %v", method, operations)
+		}
+	}
+}
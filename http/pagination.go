@@ -0,0 +1,142 @@
+package http
+
+import (
+	"encoding/base64"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/alauda/bergamot/errors"
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/alauda/bergamot/query"
+)
+
+// Pagination URL params
+const (
+	PageParam     = "page"
+	PageSizeParam = "page_size"
+	CursorParam   = "cursor"
+)
+
+// PageLimits page size limits used when parsing the paging params
+type PageLimits struct {
+	// DefaultPageSize used when page_size is not given
+	// defaults to DefaultPageLimits.DefaultPageSize
+	DefaultPageSize int
+	// MaxPageSize larger page sizes are reduced to this value
+	MaxPageSize int
+}
+
+// DefaultPageLimits limits used when none are given
+var DefaultPageLimits = PageLimits{DefaultPageSize: 20, MaxPageSize: 100}
+
+// Page standard envelope for list results
+type Page struct {
+	Count    int         `json:"count"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"page_size"`
+	NumPages int         `json:"num_pages"`
+	Next     string      `json:"next"`
+	Previous string      `json:"previous"`
+	Results  interface{} `json:"results"`
+}
+
+// GetPagingQuery parses the page, page_size and cursor URL params
+// and returns a query with the requested page.
+// Invalid params return an invalid_args error
+func (Handler) GetPagingQuery(ctx mux.Context, limits ...PageLimits) (query.Query, error) {
+	limit := DefaultPageLimits
+	if len(limits) > 0 {
+		limit = limits[0]
+	}
+	if limit.DefaultPageSize < 1 {
+		limit.DefaultPageSize = DefaultPageLimits.DefaultPageSize
+	}
+	paging := query.Paging{Page: 1, PageSize: limit.DefaultPageSize}
+	var err *errors.AlaudaError
+	var parseErr error
+	if value := ctx.URLParam(PageParam); value != "" {
+		if paging.Page, parseErr = strconv.Atoi(value); parseErr != nil || paging.Page < 1 {
+			err = getPagingError(err, PageParam, "A valid page number is required.")
+		}
+	}
+	if value := ctx.URLParam(PageSizeParam); value != "" {
+		if paging.PageSize, parseErr = strconv.Atoi(value); parseErr != nil || paging.PageSize < 1 {
+			err = getPagingError(err, PageSizeParam, "A valid page size is required.")
+		}
+	}
+	if limit.MaxPageSize > 0 && paging.PageSize > limit.MaxPageSize {
+		paging.PageSize = limit.MaxPageSize
+	}
+	if value := ctx.URLParam(CursorParam); value != "" {
+		cursor, decodeErr := DecodeCursor(value)
+		if decodeErr != nil {
+			err = getPagingError(err, CursorParam, "Invalid cursor.")
+		}
+		paging.Page = 0
+		paging.Cursor = cursor
+	}
+	if err != nil {
+		return nil, err
+	}
+	return query.New().Paginate(paging), nil
+}
+
+func getPagingError(err *errors.AlaudaError, field, message string) *errors.AlaudaError {
+	return errors.GetError("pagination", err, errors.ErrorCodeInvalidArgs).AddFieldError(field, message)
+}
+
+// RenderPage renders a page of results with the total count of items
+func (Handler) RenderPage(ctx mux.Context, paging query.Paging, count int, results interface{}) {
+	page := Page{
+		Count:    count,
+		Page:     paging.Page,
+		PageSize: paging.PageSize,
+		Results:  getResults(results),
+	}
+	if paging.PageSize > 0 {
+		page.NumPages = (count + paging.PageSize - 1) / paging.PageSize
+	}
+	Render(ctx, http.StatusOK, page)
+}
+
+// RenderCursorPage renders a page of results using cursor paging
+// next and previous are the positions of the adjacent pages, empty if there are none.
+// count is the number of results in the page as the total is unknown
+func (Handler) RenderCursorPage(ctx mux.Context, paging query.Paging, results interface{}, next, previous string) {
+	page := Page{
+		PageSize: paging.PageSize,
+		Results:  getResults(results),
+	}
+	if next != "" {
+		page.Next = EncodeCursor(next)
+	}
+	if previous != "" {
+		page.Previous = EncodeCursor(previous)
+	}
+	if value := reflect.ValueOf(page.Results); value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		page.Count = value.Len()
+	}
+	Render(ctx, http.StatusOK, page)
+}
+
+// EncodeCursor encodes a position as an opaque cursor token
+func EncodeCursor(position string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(position))
+}
+
+// DecodeCursor decodes a cursor token generated by EncodeCursor
+func DecodeCursor(cursor string) (string, error) {
+	position, err := base64.RawURLEncoding.DecodeString(cursor)
+	return string(position), err
+}
+
+// getResults returns an empty list instead of nil
+// to always render results as a JSON array
+func getResults(results interface{}) interface{} {
+	value := reflect.ValueOf(results)
+	if !value.IsValid() || ((value.Kind() == reflect.Slice) && value.IsNil()) {
+		return []interface{}{}
+	}
+	return results
+}
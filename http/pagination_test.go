@@ -0,0 +1,84 @@
+package http_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alauda/bergamot/http"
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/alauda/bergamot/log"
+)
+
+func TestPagination(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		URL      string
+		Expected int
+		Page     http.Page
+	}
+
+	table := []TestCase{
+		{"defaults", "/v1/items", 200, http.Page{Count: 45, Page: 1, PageSize: 20, NumPages: 3}},
+		{"page", "/v1/items?page=3&page_size=10", 200, http.Page{Count: 45, Page: 3, PageSize: 10, NumPages: 5}},
+		{"max page size", "/v1/items?page_size=1000", 200, http.Page{Count: 45, Page: 1, PageSize: 50, NumPages: 1}},
+		{"invalid page", "/v1/items?page=zero", 400, http.Page{}},
+		{"page out of range", "/v1/items?page=99999999999999999999", 400, http.Page{}},
+		{"zero default page size", "/v1/defaults", 200, http.Page{Count: 45, Page: 1, PageSize: 20, NumPages: 3}},
+		{"cursor", "/v1/items?cursor=" + http.EncodeCursor("10"), 200, http.Page{Count: 0, PageSize: 20, Next: http.EncodeCursor("30"), Previous: http.EncodeCursor("10")}},
+	}
+
+	server := http.NewServer(http.Config{}, log.EmptyLogger{}).Init()
+	server.AddVersionEndpointFunc(1, "/items", func(router mux.Router, server *http.Server) {
+		router.Get("", func(ctx mux.Context) {
+			handler := http.Handler{}
+			q, err := handler.GetPagingQuery(ctx, http.PageLimits{DefaultPageSize: 20, MaxPageSize: 50})
+			if err != nil {
+				handler.HandleError(err, ctx, log.EmptyLogger{})
+				return
+			}
+			paging, _ := q.GetPaging()
+			if paging.Cursor != "" {
+				handler.RenderCursorPage(ctx, paging, nil, "30", paging.Cursor)
+				return
+			}
+			handler.RenderPage(ctx, paging, 45, []int{})
+		})
+	})
+	server.AddVersionEndpointFunc(1, "/defaults", func(router mux.Router, server *http.Server) {
+		router.Get("", func(ctx mux.Context) {
+			handler := http.Handler{}
+			q, err := handler.GetPagingQuery(ctx, http.PageLimits{MaxPageSize: 50})
+			if err != nil {
+				handler.HandleError(err, ctx, log.EmptyLogger{})
+				return
+			}
+			paging, _ := q.GetPaging()
+			handler.RenderPage(ctx, paging, 45, []int{})
+		})
+	})
+	server.Boot()
+
+	for i, test := range table {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest("GET", test.URL, nil))
+		if recorder.Code != test.Expected {
+			t.Errorf("%d - %s -- expected status %d got %d: %s", i, test.Name, test.Expected, recorder.Code, recorder.Body.String())
+			continue
+		}
+		if test.Expected != 200 {
+			continue
+		}
+		var page http.Page
+		if err := json.Unmarshal(recorder.Body.Bytes(), &page); err != nil {
+			t.Fatalf("%d - %s -- unexpected error: %v", i, test.Name, err)
+		}
+		if results, ok := page.Results.([]interface{}); !ok || len(results) != 0 {
+			t.Errorf("%d - %s -- expected empty results got %#v", i, test.Name, page.Results)
+		}
+		page.Results = nil
+		if page != test.Page {
+			t.Errorf("%d - %s -- expected %+v got %+v", i, test.Name, test.Page, page)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/alauda/bergamot/auth"
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/alauda/bergamot/log"
+	"github.com/alauda/bergamot/ratelimit"
+	"github.com/alauda/bergamot/utils"
+)
+
+// Rate limit headers
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset"
+	RetryAfterHeader         = "Retry-After"
+)
+
+// RateLimitKeyFunc returns the key used to limit a request
+// requests with an empty key are not limited
+type RateLimitKeyFunc func(ctx mux.Context) string
+
+// KeyByIP limits requests by the IP of the connection
+// forwarding headers are ignored because any client can set them
+func KeyByIP(ctx mux.Context) string {
+	return "ip:" + utils.ClientIP(ctx.Request(), nil)
+}
+
+// KeyByTrustedIP limits requests by client IP using the
+// X-Forwarded-For header only for requests from the trusted proxies
+func KeyByTrustedIP(trusted []*net.IPNet) RateLimitKeyFunc {
+	return func(ctx mux.Context) string {
+		return "ip:" + utils.ClientIP(ctx.Request(), trusted)
+	}
+}
+
+// KeyByUser limits requests by the authenticated user
+// anonymous requests are limited using KeyByIP
+func KeyByUser(ctx mux.Context) string {
+	return KeyByUserOr(KeyByIP)(ctx)
+}
+
+// KeyByUserOr limits requests by the authenticated user
+// anonymous requests are limited using the fallback
+func KeyByUserOr(fallback RateLimitKeyFunc) RateLimitKeyFunc {
+	return func(ctx mux.Context) string {
+		if user := ratelimit.UserKey(ctx.Get(USER)); user != "" {
+			return "user:" + user
+		}
+		return fallback(ctx)
+	}
+}
+
+// KeyByAuthenticatedUser limits requests by the authenticated user
+// requests not authenticated by a previous middleware are authenticated
+// using the authenticator, anonymous requests are limited using the fallback
+func KeyByAuthenticatedUser(authenticator auth.Authenticator, fallback RateLimitKeyFunc) RateLimitKeyFunc {
+	byUser := KeyByUserOr(fallback)
+	return func(ctx mux.Context) string {
+		if ctx.Get(USER) == nil && authenticator != nil {
+			// invalid credentials are rejected later by the auth middlewares
+			Authenticate(ctx, authenticator)
+		}
+		return byUser(ctx)
+	}
+}
+
+// KeyByHeader limits requests by the value of a header like X-Api-Key
+// requests without the header are limited using KeyByIP
+// so omitting the header does not skip the limit
+func KeyByHeader(name string) RateLimitKeyFunc {
+	return KeyByHeaderOr(name, KeyByIP)
+}
+
+// KeyByHeaderOr limits requests by the value of a header like X-Api-Key
+// requests without the header are limited using the fallback
+func KeyByHeaderOr(name string, fallback RateLimitKeyFunc) RateLimitKeyFunc {
+	return func(ctx mux.Context) string {
+		if value := ctx.RequestHeader(name); value != "" {
+			return "header:" + value
+		}
+		return fallback(ctx)
+	}
+}
+
+// RateLimitConfig configuration for RateLimitMiddleware
+type RateLimitConfig struct {
+	Limiter ratelimit.Limiter
+	// KeyFunc defaults to KeyByIP
+	KeyFunc RateLimitKeyFunc
+	// PerRoute limits each route separately instead of all routes together
+	PerRoute bool
+}
+
+// RateLimitMiddleware limits the number of requests by key
+// rejecting requests over the limit with a too_many_requests error
+type RateLimitMiddleware struct {
+	config RateLimitConfig
+	log    log.Logger
+}
+
+// NewRateLimitMiddleware constructor function for RateLimitMiddleware
+// limiter errors are logged and the request is allowed
+func NewRateLimitMiddleware(config RateLimitConfig, logger log.Logger) *RateLimitMiddleware {
+	if config.KeyFunc == nil {
+		config.KeyFunc = KeyByIP
+	}
+	return &RateLimitMiddleware{
+		config: config,
+		log:    log.GetSafe(logger),
+	}
+}
+
+// Serve counts the request and calls the next handler if under the limit
+func (m *RateLimitMiddleware) Serve(ctx mux.Context) {
+	key := m.config.KeyFunc(ctx)
+	if key == "" {
+		ctx.Next()
+		return
+	}
+	if m.config.PerRoute {
+		key += ":" + ctx.Method() + " " + GetRouteTemplate(ctx)
+	}
+	result, err := m.config.Limiter.Allow(key)
+	if err != nil {
+		m.log.Errorf("Rate limiter failed for %s: %v", key, err)
+		ctx.Next()
+		return
+	}
+	ctx.SetHeader(RateLimitLimitHeader, strconv.Itoa(result.Limit))
+	ctx.SetHeader(RateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+	ctx.SetHeader(RateLimitResetHeader, strconv.FormatInt(ratelimit.Seconds(result.Reset), 10))
+	if !result.Allowed {
+		ctx.StopExecution()
+		ctx.SetHeader(RetryAfterHeader, strconv.FormatInt(ratelimit.Seconds(result.RetryAfter), 10))
+		Render(ctx, http.StatusTooManyRequests, NewAlaudaError(ratelimit.NewTooManyRequestsError(result)))
+		return
+	}
+	ctx.Next()
+}
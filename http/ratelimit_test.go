@@ -0,0 +1,64 @@
+package http_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alauda/bergamot/http"
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/alauda/bergamot/log"
+	"github.com/alauda/bergamot/ratelimit"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	type TestCase struct {
+		Name      string
+		APIKey    string
+		Forwarded string
+		Expected  int
+		Remaining string
+	}
+
+	table := []TestCase{
+		{"first", "key", "", 200, "0"},
+		{"over the limit", "key", "", 429, "0"},
+		{"other key", "other", "", 200, "0"},
+		{"without header by ip", "", "", 200, "0"},
+		{"spoofed forwarded ip", "", "203.0.113.1", 429, "0"},
+	}
+
+	server := http.NewServer(http.Config{}, log.EmptyLogger{}).Init()
+	server.AddMiddleware(http.NewRateLimitMiddleware(http.RateLimitConfig{
+		Limiter: ratelimit.NewMemoryLimiter(1, time.Minute),
+		KeyFunc: http.KeyByHeader("X-Api-Key"),
+	}, log.EmptyLogger{}))
+	server.AddVersionEndpointFunc(1, "/users", func(router mux.Router, server *http.Server) {
+		router.Get("", func(ctx mux.Context) { ctx.WriteString("users") })
+	})
+	server.Boot()
+
+	for i, test := range table {
+		request := httptest.NewRequest("GET", "/v1/users", nil)
+		if test.APIKey != "" {
+			request.Header.Set("X-Api-Key", test.APIKey)
+		}
+		if test.Forwarded != "" {
+			request.Header.Set("X-Forwarded-For", test.Forwarded)
+		}
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != test.Expected {
+			t.Errorf("%d - %s -- expected status %d got %d: %s", i, test.Name, test.Expected, recorder.Code, recorder.Body.String())
+		}
+		if remaining := recorder.Header().Get(http.RateLimitRemainingHeader); remaining != test.Remaining {
+			t.Errorf("%d - %s -- expected remaining %q got %q", i, test.Name, test.Remaining, remaining)
+		}
+		if test.Expected == 429 {
+			if recorder.Header().Get(http.RetryAfterHeader) == "" || !strings.Contains(recorder.Body.String(), "too_many_requests") {
+				t.Errorf("%d - %s -- expected retry after and error code: %v %s", i, test.Name, recorder.Header(), recorder.Body.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/alauda/bergamot/errors"
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/alauda/bergamot/log"
+	"github.com/alauda/bergamot/loggo"
+	"github.com/alauda/bergamot/metrics"
+)
+
+// RecoveryMiddleware recovers from panics in the next handlers
+// logging the stack and returning an unknown_issue error
+type RecoveryMiddleware struct {
+	component string
+	log       log.Logger
+	metrics   metrics.Client
+}
+
+// NewRecoveryMiddleware constructor function for RecoveryMiddleware
+// client can be nil to skip the panic counter
+func NewRecoveryMiddleware(component string, logger log.Logger, client metrics.Client) *RecoveryMiddleware {
+	return &RecoveryMiddleware{
+		component: component,
+		log:       log.GetSafe(logger),
+		metrics:   client,
+	}
+}
+
+// Serve calls the next handlers recovering from any panic
+func (m *RecoveryMiddleware) Serve(ctx mux.Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			m.recover(ctx, r)
+		}
+	}()
+	ctx.Next()
+}
+
+func (m *RecoveryMiddleware) recover(ctx mux.Context, r interface{}) {
+	fields := loggo.Fields{
+		"method": ctx.Method(),
+		"path":   ctx.Path(),
+		"panic":  fmt.Sprint(r),
+		"stack":  string(debug.Stack()),
+	}
+	if requestID := GetRequestID(ctx); requestID != "" {
+		fields["request_id"] = requestID
+	}
+	m.log.StError("panic", fields)
+	if m.metrics != nil {
+		m.metrics.Incr("comp."+m.component+".panics", []string{"action:" + GetRouteTemplate(ctx)}, 1)
+	}
+	ctx.StopExecution()
+	Render(ctx, http.StatusInternalServerError, NewAlaudaError(errors.New(m.component, errors.ErrorCodeUnknownIssue)))
+}
@@ -0,0 +1,85 @@
+package http_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alauda/bergamot/http"
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/alauda/bergamot/log"
+	"github.com/alauda/bergamot/metrics"
+)
+
+type countMetrics struct {
+	metrics.ClosedClient
+	incr map[string]int
+}
+
+func (c *countMetrics) Incr(name string, tags []string, rate float64) error {
+	c.incr[name]++
+	return nil
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	client := &countMetrics{incr: map[string]int{}}
+	logger := &recordLogger{}
+	server := http.NewServer(http.Config{Component: "test", AddRequestID: true}, log.EmptyLogger{}).Init()
+	server.AddMiddleware(http.NewRecoveryMiddleware("test", logger, client))
+	server.GetApp().Get("/panic", func(ctx mux.Context) {
+		panic("boom")
+	})
+	server.Boot()
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "/panic", nil)
+	request.Header.Set(http.RequestIDHeader, "abc")
+	server.ServeHTTP(recorder, request)
+
+	if recorder.Code != 500 {
+		t.Errorf("expected status 500 got %d", recorder.Code)
+	}
+	var body struct {
+		Errors []struct {
+			Code   string `json:"code"`
+			Source string `json:"source"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil || len(body.Errors) != 1 || body.Errors[0].Code != "unknown_issue" || body.Errors[0].Source != "test" {
+		t.Errorf("unexpected body %s: %v", recorder.Body.String(), err)
+	}
+	if client.incr["comp.test.panics"] != 1 {
+		t.Errorf("expected panic counter, got: %v", client.incr)
+	}
+	if len(logger.fields) != 1 || logger.fields[0]["panic"] != "boom" || logger.fields[0]["request_id"] != "abc" {
+		t.Errorf("expected panic log, got: %v", logger.fields)
+	}
+}
+
+func TestRecoveryConfig(t *testing.T) {
+	client := &countMetrics{incr: map[string]int{}}
+	accessLogger := &recordLogger{}
+	server := http.NewServer(http.Config{
+		Component:   "test",
+		AddRecovery: true,
+		AddLog:      true,
+		LogFunc:     http.NewAccessLog(http.AccessLogConfig{Fields: []string{http.AccessLogStatus}}, accessLogger).Serve,
+		Metrics:     client,
+	}, log.EmptyLogger{}).Init()
+	server.GetApp().Get("/panic", func(ctx mux.Context) {
+		panic("boom")
+	})
+	server.Boot()
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest("GET", "/panic", nil))
+	if recorder.Code != 500 {
+		t.Errorf("expected status 500 got %d", recorder.Code)
+	}
+	if client.incr["comp.test.panics"] != 1 {
+		t.Errorf("expected panic counter, got: %v", client.incr)
+	}
+	if len(accessLogger.fields) != 1 || accessLogger.fields[0][http.AccessLogStatus] != 500 {
+		t.Errorf("expected access log with status 500, got: %v", accessLogger.fields)
+	}
+}
@@ -0,0 +1,226 @@
+package http
+
+import (
+	"encoding/json"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alauda/bergamot/errors"
+	"github.com/alauda/bergamot/http/mux"
+
+	"github.com/golang/protobuf/proto"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Negotiated content types
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeYAML     = "application/x-yaml"
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeNDJSON   = "application/x-ndjson"
+	ContentTypeSSE      = "text/event-stream"
+)
+
+// contentTypeAliases other media types accepted for each content type
+var contentTypeAliases = map[string]string{
+	ContentTypeJSON:                   ContentTypeJSON,
+	"text/json":                       ContentTypeJSON,
+	"application/*":                   ContentTypeJSON,
+	"*/*":                             ContentTypeJSON,
+	ContentTypeYAML:                   ContentTypeYAML,
+	"application/yaml":                ContentTypeYAML,
+	"text/yaml":                       ContentTypeYAML,
+	"text/x-yaml":                     ContentTypeYAML,
+	ContentTypeProtobuf:               ContentTypeProtobuf,
+	"application/protobuf":            ContentTypeProtobuf,
+	"application/vnd.google.protobuf": ContentTypeProtobuf,
+}
+
+// Render writes v using the format negotiated with the Accept header
+// JSON is used by default and when v can not be encoded in the accepted formats,
+// protobuf is only available for values implementing proto.Message and AlaudaError
+func Render(ctx mux.Context, status int, v interface{}) error {
+	contentType := Negotiate(ctx.RequestHeader("Accept"), v)
+	var (
+		data []byte
+		err  error
+	)
+	switch contentType {
+	case ContentTypeYAML:
+		data, err = marshalYAML(v)
+	case ContentTypeProtobuf:
+		data, err = marshalProtobuf(v)
+	default:
+		return ctx.JSON(status, v)
+	}
+	if err != nil {
+		return err
+	}
+	ctx.ResponseWriter().Header().Set("Content-Type", contentType)
+	ctx.SetStatusCode(status)
+	_, err = ctx.Write(data)
+	return err
+}
+
+// Render writes v using the format negotiated with the Accept header
+func (Handler) Render(ctx mux.Context, status int, v interface{}) error {
+	return Render(ctx, status, v)
+}
+
+// Negotiate returns the content type used to render v
+// for the given Accept header
+func Negotiate(accept string, v interface{}) string {
+	for _, mediaType := range parseAccept(accept) {
+		contentType, ok := contentTypeAliases[mediaType]
+		if !ok {
+			continue
+		}
+		if contentType == ContentTypeProtobuf && !isProtobuf(v) {
+			continue
+		}
+		return contentType
+	}
+	return ContentTypeJSON
+}
+
+// parseAccept returns the media types of the Accept header
+// sorted by quality, media types with q=0 are not included
+func parseAccept(accept string) []string {
+	type mediaRange struct {
+		mediaType string
+		quality   float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality > 0 {
+			ranges = append(ranges, mediaRange{mediaType, quality})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+	mediaTypes := make([]string, len(ranges))
+	for i, r := range ranges {
+		mediaTypes[i] = r.mediaType
+	}
+	return mediaTypes
+}
+
+// marshalYAML marshals v using the JSON field names
+func marshalYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err = yaml.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(value)
+}
+
+func isProtobuf(v interface{}) bool {
+	switch v.(type) {
+	case proto.Message, *AlaudaError:
+		return true
+	}
+	return false
+}
+
+func marshalProtobuf(v interface{}) ([]byte, error) {
+	if alaudaErr, ok := v.(*AlaudaError); ok {
+		v = alaudaErr.ToProto()
+	}
+	return proto.Marshal(v.(proto.Message))
+}
+
+// ErrorsMessage protobuf message of the AlaudaError envelope
+//
+//	message Errors { repeated Error errors = 1; }
+//	message Error { string source = 1; string message = 2; string code = 3; repeated Field fields = 4; }
+//	message Field { string name = 1; repeated string messages = 2; }
+type ErrorsMessage struct {
+	Errors []*ErrorMessage `protobuf:"bytes,1,rep,name=errors" json:"errors,omitempty"`
+}
+
+// Reset resets the message
+func (m *ErrorsMessage) Reset() { *m = ErrorsMessage{} }
+
+// String returns the message in text format
+func (m *ErrorsMessage) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage marks the struct as a protobuf message
+func (*ErrorsMessage) ProtoMessage() {}
+
+// ErrorMessage protobuf message of an error in the AlaudaError envelope
+type ErrorMessage struct {
+	Source  string          `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
+	Message string          `protobuf:"bytes,2,opt,name=message" json:"message,omitempty"`
+	Code    string          `protobuf:"bytes,3,opt,name=code" json:"code,omitempty"`
+	Fields  []*FieldMessage `protobuf:"bytes,4,rep,name=fields" json:"fields,omitempty"`
+}
+
+// Reset resets the message
+func (m *ErrorMessage) Reset() { *m = ErrorMessage{} }
+
+// String returns the message in text format
+func (m *ErrorMessage) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage marks the struct as a protobuf message
+func (*ErrorMessage) ProtoMessage() {}
+
+// FieldMessage protobuf message of a field error
+type FieldMessage struct {
+	Name     string   `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Messages []string `protobuf:"bytes,2,rep,name=messages" json:"messages,omitempty"`
+}
+
+// Reset resets the message
+func (m *FieldMessage) Reset() { *m = FieldMessage{} }
+
+// String returns the message in text format
+func (m *FieldMessage) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage marks the struct as a protobuf message
+func (*FieldMessage) ProtoMessage() {}
+
+// ToProto converts the envelope to its protobuf message
+// errors that are not AlaudaError are converted to unknown_issue errors
+func (e *AlaudaError) ToProto() *ErrorsMessage {
+	message := &ErrorsMessage{Errors: make([]*ErrorMessage, 0, len(e.Errors))}
+	for _, err := range e.Errors {
+		alaudaErr := errors.NewCommon("", err)
+		if alaudaErr == nil {
+			continue
+		}
+		item := &ErrorMessage{
+			Source:  alaudaErr.Source,
+			Message: alaudaErr.Message,
+			Code:    string(alaudaErr.Code),
+		}
+		if len(alaudaErr.Fields) > 0 {
+			names := make([]string, 0, len(alaudaErr.Fields[0]))
+			for name := range alaudaErr.Fields[0] {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				item.Fields = append(item.Fields, &FieldMessage{Name: name, Messages: alaudaErr.Fields[0][name]})
+			}
+		}
+		message.Errors = append(message.Errors, item)
+	}
+	return message
+}
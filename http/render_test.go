@@ -0,0 +1,111 @@
+package http_test
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alauda/bergamot/errors"
+	"github.com/alauda/bergamot/http"
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/alauda/bergamot/log"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func TestRender(t *testing.T) {
+	type TestCase struct {
+		Name        string
+		Path        string
+		Accept      string
+		ContentType string
+		Contains    string
+	}
+
+	table := []TestCase{
+		{"default", "/v1/items", "", http.ContentTypeJSON, `"page_size":20`},
+		{"yaml", "/v1/items", "application/yaml", http.ContentTypeYAML, "page_size: 20"},
+		{"quality", "/v1/items", "application/json;q=0.5, text/yaml", http.ContentTypeYAML, "page_size: 20"},
+		{"protobuf not supported", "/v1/items", "application/x-protobuf", http.ContentTypeJSON, `"page_size":20`},
+		{"yaml error", "/v1/missing", "application/x-yaml", http.ContentTypeYAML, "code: resource_not_exist"},
+		{"protobuf error", "/v1/missing", "application/x-protobuf", http.ContentTypeProtobuf, "resource_not_exist"},
+	}
+
+	server := http.NewServer(http.Config{}, log.EmptyLogger{}).Init()
+	server.AddVersionEndpointFunc(1, "", func(router mux.Router, server *http.Server) {
+		router.Get("/items", func(ctx mux.Context) {
+			http.Handler{}.Render(ctx, 200, http.Page{PageSize: 20, Results: []string{}})
+		})
+		router.Get("/missing", func(ctx mux.Context) {
+			http.Handler{}.HandleError(errors.New("test", errors.ErrorCodeResourceNotFound), ctx, log.EmptyLogger{})
+		})
+	})
+	server.Boot()
+
+	for i, test := range table {
+		request := httptest.NewRequest("GET", test.Path, nil)
+		request.Header.Set("Accept", test.Accept)
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, test.ContentType) {
+			t.Errorf("%d - %s -- expected content type %s got %s", i, test.Name, test.ContentType, contentType)
+		}
+		body := recorder.Body.String()
+		if test.ContentType == http.ContentTypeProtobuf {
+			message := &http.ErrorsMessage{}
+			if err := proto.Unmarshal(recorder.Body.Bytes(), message); err != nil || len(message.Errors) != 1 {
+				t.Errorf("%d - %s -- unexpected protobuf body %v: %v", i, test.Name, message, err)
+				continue
+			}
+			body = message.Errors[0].Code
+		}
+		if !strings.Contains(body, test.Contains) {
+			t.Errorf("%d - %s -- expected %s in %s", i, test.Name, test.Contains, body)
+		}
+	}
+}
+
+func TestGzip(t *testing.T) {
+	type TestCase struct {
+		Name           string
+		Path           string
+		AcceptEncoding string
+		Compressed     bool
+	}
+
+	table := []TestCase{
+		{"large", "/large", "gzip, deflate", true},
+		{"small", "/small", "gzip", false},
+		{"not accepted", "/large", "gzip;q=0", false},
+	}
+
+	large := strings.Repeat("bergamot ", 200)
+	server := http.NewServer(http.Config{Gzip: true, GzipMinSize: 512}, log.EmptyLogger{}).Init()
+	server.GetApp().Get("/large", func(ctx mux.Context) { ctx.WriteString(large) })
+	server.GetApp().Get("/small", func(ctx mux.Context) { ctx.WriteString("small") })
+	server.Boot()
+
+	for i, test := range table {
+		request := httptest.NewRequest("GET", test.Path, nil)
+		request.Header.Set("Accept-Encoding", test.AcceptEncoding)
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		compressed := recorder.Header().Get("Content-Encoding") == "gzip"
+		if compressed != test.Compressed {
+			t.Errorf("%d - %s -- expected compressed %v got headers %v", i, test.Name, test.Compressed, recorder.Header())
+			continue
+		}
+		if !compressed {
+			continue
+		}
+		reader, err := gzip.NewReader(recorder.Body)
+		if err != nil {
+			t.Fatalf("%d - %s -- unexpected error: %v", i, test.Name, err)
+		}
+		if body, _ := ioutil.ReadAll(reader); string(body) != large {
+			t.Errorf("%d - %s -- unexpected body %q", i, test.Name, body)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package http
+
+import (
+	"github.com/alauda/bergamot/contexts"
+	"github.com/alauda/bergamot/http/mux"
+)
+
+// RequestIDHeader header used to receive and return the request ID
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDMiddleware reads the request ID from the X-Request-ID header
+// or generates a new one if missing or invalid, stores it in the request context
+// and returns it in the response header
+type RequestIDMiddleware struct{}
+
+// NewRequestIDMiddleware constructor for RequestIDMiddleware
+func NewRequestIDMiddleware() RequestIDMiddleware {
+	return RequestIDMiddleware{}
+}
+
+// Serve sets the request ID and calls the next handler
+func (RequestIDMiddleware) Serve(ctx mux.Context) {
+	requestID := ctx.RequestHeader(RequestIDHeader)
+	if !contexts.IsValidRequestID(requestID) {
+		requestID = contexts.NewRequestID()
+	}
+	ctx.Set(REQUESTID, requestID)
+	ctx.SetHeader(RequestIDHeader, requestID)
+	ctx.Next()
+}
+
+// GetRequestID returns the request ID stored in the request context
+// or an empty string if not set
+func GetRequestID(ctx mux.Context) string {
+	requestID, _ := ctx.Get(REQUESTID).(string)
+	return requestID
+}
@@ -0,0 +1,73 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/alauda/bergamot/http/mux"
+)
+
+type responseSizeKey struct{}
+
+// sizeResponseWriter counts the bytes written in the response body
+type sizeResponseWriter struct {
+	http.ResponseWriter
+	size int64
+}
+
+// Write writes to the underlying writer counting the written bytes
+func (w *sizeResponseWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// Flush flushes the underlying writer if supported
+func (w *sizeResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack hijacks the underlying writer if supported
+func (w *sizeResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, errors.New("hijack is not supported by this ResponseWriter")
+}
+
+// CloseNotify returns the close notification channel of the underlying writer
+func (w *sizeResponseWriter) CloseNotify() <-chan bool {
+	if notifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+// Push pushes using the underlying writer if supported
+func (w *sizeResponseWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// countResponseSize router wrapper that keeps track of the response size
+func countResponseSize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := &sizeResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(writer, r.WithContext(context.WithValue(r.Context(), responseSizeKey{}, writer)))
+	})
+}
+
+// GetResponseSize returns the number of bytes of the response body written so far
+func GetResponseSize(ctx mux.Context) int64 {
+	if writer, ok := ctx.Request().Context().Value(responseSizeKey{}).(*sizeResponseWriter); ok {
+		return writer.size
+	}
+	return 0
+}
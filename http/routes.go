@@ -0,0 +1,18 @@
+package http
+
+import (
+	"github.com/alauda/bergamot/http/mux"
+)
+
+// UnmatchedRoute route template of requests that did not match any route
+// used instead of the request path to keep metric tags bounded
+const UnmatchedRoute = "unmatched"
+
+// GetRouteTemplate returns the registered route path matching the request
+// like /v1/users/:id, returns UnmatchedRoute if no route matches
+func GetRouteTemplate(ctx mux.Context) string {
+	if template := ctx.Route(); template != "" {
+		return template
+	}
+	return UnmatchedRoute
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alauda/bergamot/http/mux"
+)
+
+// NDJSONStream writes newline delimited JSON values
+// flushing after each value, used for long-running endpoints
+type NDJSONStream struct {
+	ctx mux.Context
+}
+
+// NewNDJSONStream starts a newline delimited JSON response
+func (Handler) NewNDJSONStream(ctx mux.Context) *NDJSONStream {
+	startStream(ctx, ContentTypeNDJSON)
+	return &NDJSONStream{ctx: ctx}
+}
+
+// Send writes a value followed by a new line
+func (s *NDJSONStream) Send(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err = s.ctx.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	s.ctx.Flush()
+	return nil
+}
+
+// Done returns a channel closed when the client disconnects
+func (s *NDJSONStream) Done() <-chan struct{} {
+	return s.ctx.Request().Context().Done()
+}
+
+var (
+	// lineBreaks removes line breaks from single line fields
+	lineBreaks = strings.NewReplacer("\r\n", "", "\r", "", "\n", "")
+	// newLines normalizes line breaks to split the data lines
+	newLines = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+)
+
+// Event Server-Sent Event
+type Event struct {
+	// ID sets the last event ID of the client, ignored if empty
+	// line breaks are removed
+	ID string
+	// Name event type, the client uses message if empty
+	// line breaks are removed
+	Name string
+	// Data strings are sent as they are, other values are encoded as JSON
+	Data interface{}
+	// Retry reconnection time of the client, ignored if zero
+	Retry time.Duration
+}
+
+// EventStream writes Server-Sent Events
+// flushing after each event
+type EventStream struct {
+	ctx mux.Context
+}
+
+// NewEventStream starts a Server-Sent Events response
+func (Handler) NewEventStream(ctx mux.Context) *EventStream {
+	ctx.SetHeader("Cache-Control", "no-cache")
+	ctx.SetHeader("Connection", "keep-alive")
+	// disables response buffering in nginx
+	ctx.SetHeader("X-Accel-Buffering", "no")
+	startStream(ctx, ContentTypeSSE)
+	return &EventStream{ctx: ctx}
+}
+
+// Send writes an event
+func (s *EventStream) Send(event Event) error {
+	var buffer bytes.Buffer
+	if event.ID != "" {
+		fmt.Fprintf(&buffer, "id: %s\n", lineBreaks.Replace(event.ID))
+	}
+	if event.Name != "" {
+		fmt.Fprintf(&buffer, "event: %s\n", lineBreaks.Replace(event.Name))
+	}
+	if event.Retry > 0 {
+		fmt.Fprintf(&buffer, "retry: %d\n", event.Retry/time.Millisecond)
+	}
+	data, ok := event.Data.(string)
+	if !ok && event.Data != nil {
+		encoded, err := json.Marshal(event.Data)
+		if err != nil {
+			return err
+		}
+		data = string(encoded)
+	}
+	for _, line := range strings.Split(newLines.Replace(data), "\n") {
+		fmt.Fprintf(&buffer, "data: %s\n", line)
+	}
+	buffer.WriteByte('\n')
+	if _, err := s.ctx.Write(buffer.Bytes()); err != nil {
+		return err
+	}
+	s.ctx.Flush()
+	return nil
+}
+
+// Comment writes a comment, used as a keep alive
+func (s *EventStream) Comment(comment string) error {
+	if _, err := s.ctx.WriteString(": " + lineBreaks.Replace(comment) + "\n\n"); err != nil {
+		return err
+	}
+	s.ctx.Flush()
+	return nil
+}
+
+// Done returns a channel closed when the client disconnects
+func (s *EventStream) Done() <-chan struct{} {
+	return s.ctx.Request().Context().Done()
+}
+
+// startStream sends the headers of a streaming response
+func startStream(ctx mux.Context, contentType string) {
+	ctx.ResponseWriter().Header().Set("Content-Type", contentType)
+	ctx.SetStatusCode(http.StatusOK)
+	// writing nothing sends the status code
+	ctx.Write(nil)
+	ctx.Flush()
+}
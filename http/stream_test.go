@@ -0,0 +1,53 @@
+package http_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alauda/bergamot/http"
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/alauda/bergamot/log"
+)
+
+func TestStreams(t *testing.T) {
+	type TestCase struct {
+		Name        string
+		Path        string
+		ContentType string
+		Expected    string
+	}
+
+	table := []TestCase{
+		{"ndjson", "/v1/logs/ndjson", http.ContentTypeNDJSON, "{\"line\":1}\n{\"line\":2}\n"},
+		{"sse", "/v1/logs/sse", http.ContentTypeSSE, "id: 1\nevent: log\nretry: 1000\ndata: {\"line\":1}\n\ndata: multi\ndata: line\n\nid: 2data: x\nevent: logretry: 0\ndata: a\ndata: b\n\n: ping\n\n"},
+	}
+
+	server := http.NewServer(http.Config{}, log.EmptyLogger{}).Init()
+	server.AddVersionEndpointFunc(1, "/logs", func(router mux.Router, server *http.Server) {
+		router.Get("/ndjson", func(ctx mux.Context) {
+			stream := http.Handler{}.NewNDJSONStream(ctx)
+			stream.Send(map[string]int{"line": 1})
+			stream.Send(map[string]int{"line": 2})
+		})
+		router.Get("/sse", func(ctx mux.Context) {
+			stream := http.Handler{}.NewEventStream(ctx)
+			stream.Send(http.Event{ID: "1", Name: "log", Data: map[string]int{"line": 1}, Retry: time.Second})
+			stream.Send(http.Event{Data: "multi\nline"})
+			stream.Send(http.Event{ID: "2\ndata: x", Name: "log\r\nretry: 0", Data: "a\rb"})
+			stream.Comment("ping")
+		})
+	})
+	server.Boot()
+
+	for i, test := range table {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest("GET", test.Path, nil))
+		if contentType := recorder.Header().Get("Content-Type"); contentType != test.ContentType {
+			t.Errorf("%d - %s -- expected content type %s got %s", i, test.Name, test.ContentType, contentType)
+		}
+		if !recorder.Flushed || recorder.Body.String() != test.Expected {
+			t.Errorf("%d - %s -- expected flushed body %q got %q", i, test.Name, test.Expected, recorder.Body.String())
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package bergamot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/alauda/bergamot/log"
+)
+
+// Shutdowner a server that can be gracefully stopped
+// servers that do not implement it will be abandoned when the app stops
+type Shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// Errors collection of errors returned by the app lifecycle
+type Errors []error
+
+// Error satisfies the error interface
+func (e Errors) Error() string {
+	var buffer bytes.Buffer
+	for i, err := range e {
+		if i > 0 {
+			buffer.WriteString("; ")
+		}
+		buffer.WriteString(err.Error())
+	}
+	return buffer.String()
+}
+
+// Err returns the collection as an error or nil if empty
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+type serverResult struct {
+	name string
+	err  error
+}
+
+// Run starts all servers concurrently and blocks until the context is done,
+// a SIGINT or SIGTERM is received or any of the servers stops.
+// Without servers it still blocks until the context is done or a signal is received.
+// When the reload mode is enabled a SIGHUP will reload the configuration.
+// All servers are then shutdown using the ShutdownTimeout deadline and
+// all datasources are closed in reverse order of creation.
+// Returns all errors combined
+func (a *App) Run(ctx context.Context) error {
+	logger := log.GetSafe(a.log)
+	signals := make(chan os.Signal, 1)
+	a.reloadLock.Lock()
+	if a.reload {
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	} else {
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	}
+	a.reloadLock.Unlock()
+	defer signal.Stop(signals)
+
+	var errs Errors
+	results := make(chan serverResult, len(a.Servers))
+	for name, server := range a.Servers {
+		go func(name string, server Server) {
+			results <- serverResult{name: name, err: server.Start()}
+		}(name, server)
+	}
+	running := len(a.Servers)
+
+serve:
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Infof("Context done, shutting down: %v", ctx.Err())
+			break serve
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				logger.Infof("Received signal %v, reloading configuration", sig)
+				a.Reload()
+				continue
+			}
+			logger.Infof("Received signal %v, shutting down", sig)
+			break serve
+		case res := <-results:
+			running--
+			logger.Errorf("Server %s stopped, shutting down: %v", res.name, res.err)
+			errs = append(errs, getServerError(res))
+			break serve
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.getShutdownTimeout())
+	defer cancel()
+	for _, name := range a.getServerNames() {
+		if shutdowner, ok := a.Servers[name].(Shutdowner); ok {
+			if err := shutdowner.Shutdown(shutdownCtx); err != nil {
+				errs = append(errs, fmt.Errorf("servers.%s: shutdown: %v", name, err))
+			}
+		}
+	}
+
+wait:
+	for running > 0 {
+		select {
+		case res := <-results:
+			running--
+			if res.err != nil {
+				errs = append(errs, fmt.Errorf("servers.%s: %v", res.name, res.err))
+			}
+		case <-shutdownCtx.Done():
+			errs = append(errs, fmt.Errorf("%d servers did not stop before deadline", running))
+			break wait
+		}
+	}
+
+	if err := a.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	return errs.Err()
+}
+
+// Close closes all datasources in reverse order of creation
+// datasources that do not implement io.Closer are ignored
+func (a *App) Close() error {
+	var errs Errors
+	names := a.getDatasourceNames()
+	for i := len(names) - 1; i >= 0; i-- {
+		if closer, ok := a.Datasources[names[i]].(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("datasources.%s: close: %v", names[i], err))
+			}
+		}
+	}
+	return errs.Err()
+}
+
+// getShutdownTimeout reads the timeout under the reload lock
+// as it can be changed by a reload
+func (a *App) getShutdownTimeout() time.Duration {
+	a.reloadLock.Lock()
+	defer a.reloadLock.Unlock()
+	if a.ShutdownTimeout <= 0 {
+		return defaultShutdownTimeout
+	}
+	return a.ShutdownTimeout
+}
+
+// getDatasourceNames returns the datasource names in creation order
+func (a *App) getDatasourceNames() []string {
+	names := make([]string, 0, len(a.Datasources))
+	for name := range a.Datasources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getServerNames returns the server names in creation order
+func (a *App) getServerNames() []string {
+	names := make([]string, 0, len(a.Servers))
+	for name := range a.Servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func getServerError(res serverResult) error {
+	if res.err == nil {
+		return fmt.Errorf("servers.%s: stopped unexpectedly", res.name)
+	}
+	return fmt.Errorf("servers.%s: %v", res.name, res.err)
+}
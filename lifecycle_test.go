@@ -0,0 +1,158 @@
+package bergamot_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alauda/bergamot"
+	"github.com/spf13/viper"
+)
+
+type fakeServer struct {
+	err  error
+	stop chan struct{}
+}
+
+func newFakeServer(err error) *fakeServer {
+	return &fakeServer{err: err, stop: make(chan struct{})}
+}
+
+func (*fakeServer) Kind() string { return "fake" }
+
+func (s *fakeServer) Start() error {
+	if s.err != nil {
+		return s.err
+	}
+	<-s.stop
+	return nil
+}
+
+func (s *fakeServer) Shutdown(ctx context.Context) error {
+	close(s.stop)
+	return nil
+}
+
+type closeRecorder struct {
+	name   string
+	closed *[]string
+}
+
+func (closeRecorder) Kind() string { return "recorder" }
+
+func (c closeRecorder) Close() error {
+	*c.closed = append(*c.closed, c.name)
+	return nil
+}
+
+func TestAppRun(t *testing.T) {
+	type TestCase struct {
+		Name      string
+		Servers   map[string]bergamot.Server
+		Cancel    bool
+		ErrLength int
+	}
+
+	table := []TestCase{
+		{
+			"context cancelled",
+			map[string]bergamot.Server{"a": newFakeServer(nil), "b": newFakeServer(nil)},
+			true,
+			0,
+		},
+		{
+			"server failure",
+			map[string]bergamot.Server{"a": newFakeServer(nil), "b": newFakeServer(errors.New("bind failed"))},
+			false,
+			1,
+		},
+	}
+
+	for i, test := range table {
+		var closed []string
+		app := &bergamot.App{
+			Servers: test.Servers,
+			Datasources: map[string]bergamot.Datasource{
+				"a": closeRecorder{"a", &closed},
+				"b": closeRecorder{"b", &closed},
+			},
+			ShutdownTimeout: time.Second,
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		if test.Cancel {
+			cancel()
+		}
+		err := app.Run(ctx)
+		cancel()
+		if test.ErrLength == 0 && err != nil {
+			t.Errorf("%d - %s -- unexpected error: %v", i, test.Name, err)
+		}
+		if errs, _ := err.(bergamot.Errors); len(errs) != test.ErrLength {
+			t.Errorf("%d - %s -- expected %d errors got: %v", i, test.Name, test.ErrLength, err)
+		}
+		if len(closed) != 2 || closed[0] != "b" || closed[1] != "a" {
+			t.Errorf("%d - %s -- expected datasources closed in reverse order got: %v", i, test.Name, closed)
+		}
+	}
+}
+
+func TestAppRunWithoutServers(t *testing.T) {
+	app := &bergamot.App{ShutdownTimeout: time.Second}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run(ctx)
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("expected run to block until the context is done, returned: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// failingParser builds recorded datasources and fails to build servers
+type failingParser struct {
+	closed *[]string
+}
+
+func (p failingParser) GetDatasources(*viper.Viper) (map[string]bergamot.Datasource, error) {
+	return map[string]bergamot.Datasource{
+		"a": closeRecorder{"a", p.closed},
+		"b": closeRecorder{"b", p.closed},
+	}, nil
+}
+
+func (failingParser) GetServers(*viper.Viper) (map[string]bergamot.Server, error) {
+	return nil, errors.New("bind failed")
+}
+
+func TestNewClosesDatasourcesOnError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bergamot")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "config.yaml")
+	if err = ioutil.WriteFile(file, []byte("component: comp\nlog_level: error\n"), 0644); err != nil {
+		t.Fatalf("unexpected error writing config: %v", err)
+	}
+	config := viper.New()
+	config.SetConfigFile(file)
+
+	var closed []string
+	app, err := bergamot.New(config, failingParser{&closed})
+	if err == nil || app != nil {
+		t.Fatalf("expected servers error got app %v and error %v", app, err)
+	}
+	if len(closed) != 2 || closed[0] != "b" || closed[1] != "a" {
+		t.Errorf("expected built datasources closed in reverse order got: %v", closed)
+	}
+}
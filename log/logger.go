@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/alauda/bergamot/contexts"
@@ -87,6 +88,21 @@ func SetLevel(level Level) {
 	loggo.ConfigureLoggers(config)
 }
 
+// ParseLevel parses a level name: trace, debug, info or error
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(name) {
+	case "trace":
+		return LevelTrace, nil
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelTrace, fmt.Errorf("unknown log level %q", name)
+}
+
 // GetFields get fields using a context
 func GetFields(ctx context.Context) (fields loggo.Fields) {
 	fields = loggo.Fields{}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"io"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// SampledClient client that scales the sample rate of every call
+// by a global rate that can be changed at runtime
+type SampledClient struct {
+	client Client
+	// rate stored as float64 bits to be accessed atomically
+	rate uint64
+}
+
+// NewSampledClient constructor for SampledClient
+// a rate of 1 will keep the sample rate of every call
+func NewSampledClient(client Client, rate float64) *SampledClient {
+	c := &SampledClient{client: client}
+	c.SetRate(rate)
+	return c
+}
+
+// SetRate sets the global sample rate
+// values out of the (0, 1] range will be ignored and the rate set to 1
+func (c *SampledClient) SetRate(rate float64) {
+	if rate <= 0 || rate > 1 {
+		rate = 1
+	}
+	atomic.StoreUint64(&c.rate, math.Float64bits(rate))
+}
+
+// Rate returns the global sample rate
+func (c *SampledClient) Rate() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&c.rate))
+}
+
+func (c *SampledClient) scale(rate float64) float64 {
+	return rate * c.Rate()
+}
+
+// Gauge measures the value of a metric at a particular time
+func (c *SampledClient) Gauge(name string, value float64, tags []string, rate float64) error {
+	return c.client.Gauge(name, value, tags, c.scale(rate))
+}
+
+// Count tracks how many times something happened
+func (c *SampledClient) Count(name string, value int64, tags []string, rate float64) error {
+	return c.client.Count(name, value, tags, c.scale(rate))
+}
+
+// Histogram tracks the statistical distribution of a set of values
+func (c *SampledClient) Histogram(name string, value float64, tags []string, rate float64) error {
+	return c.client.Histogram(name, value, tags, c.scale(rate))
+}
+
+// Decr is just Count of -1
+func (c *SampledClient) Decr(name string, tags []string, rate float64) error {
+	return c.client.Decr(name, tags, c.scale(rate))
+}
+
+// Incr is just Count of 1
+func (c *SampledClient) Incr(name string, tags []string, rate float64) error {
+	return c.client.Incr(name, tags, c.scale(rate))
+}
+
+// Set counts the number of unique elements in a group
+func (c *SampledClient) Set(name string, value string, tags []string, rate float64) error {
+	return c.client.Set(name, value, tags, c.scale(rate))
+}
+
+// Timing sends timing information
+func (c *SampledClient) Timing(name string, value time.Duration, tags []string, rate float64) error {
+	return c.client.Timing(name, value, tags, c.scale(rate))
+}
+
+// TimeInMilliseconds sends timing information in milliseconds
+func (c *SampledClient) TimeInMilliseconds(name string, value float64, tags []string, rate float64) error {
+	return c.client.TimeInMilliseconds(name, value, tags, c.scale(rate))
+}
+
+// Close flushes and closes the wrapped client if it supports it
+func (c *SampledClient) Close() error {
+	if closer, ok := c.client.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -33,6 +33,8 @@ func NewMetrics(component string, sampleRate float64, client metrics.Client) Bas
 			"request":  "comp." + component + ".requests.%d",
 			"mLatency": "comp." + component + ".requests.%s.latency",
 			"mRequest": "comp." + component + ".requests.%s.%d",
+			"reqSize":  "comp." + component + ".requests.%s.request_size",
+			"resSize":  "comp." + component + ".requests.%s.response_size",
 		},
 	}
 }
@@ -55,8 +57,15 @@ func (mw BaseMetrics) getTag(key, value string) string {
 
 // GenerateMetrics API to automatically generate metrics for a given endpoint
 func (mw BaseMetrics) GenerateMetrics(begin time.Time, module, method, action string, err error) {
+	mw.GenerateStatusMetrics(begin, module, action, mw.getStatus(err))
+}
+
+// GenerateStatusMetrics generates the same metrics as GenerateMetrics
+// using the given status code, extra tags are added to the specific metrics
+func (mw BaseMetrics) GenerateStatusMetrics(begin time.Time, module, action string, status int, tags ...string) {
 	// converting difference to milliseconds
 	milliseconds := time.Since(begin).Seconds() * 1e3
+	specificTags := append([]string{mw.getTag("action", action)}, tags...)
 
 	// general latency
 	mw.metrics.Gauge(
@@ -69,7 +78,7 @@ func (mw BaseMetrics) GenerateMetrics(begin time.Time, module, method, action st
 	)
 	// general request
 	mw.metrics.Count(
-		fmt.Sprintf(mw.metMap["request"], mw.getStatus(err)),
+		fmt.Sprintf(mw.metMap["request"], status),
 		// value
 		1,
 		// tags
@@ -82,16 +91,38 @@ func (mw BaseMetrics) GenerateMetrics(begin time.Time, module, method, action st
 		// value
 		milliseconds,
 		// tags
-		[]string{mw.getTag("action", action)},
+		specificTags,
 		mw.rate,
 	)
 	// specific request
 	mw.metrics.Count(
-		fmt.Sprintf(mw.metMap["mRequest"], module, mw.getStatus(err)),
+		fmt.Sprintf(mw.metMap["mRequest"], module, status),
 		// value
 		1,
 		// tags
-		[]string{mw.getTag("action", action)},
+		specificTags,
 		mw.rate,
 	)
 }
+
+// GenerateSizeMetrics generates request and response size histograms for a given endpoint
+// negative sizes are ignored
+func (mw BaseMetrics) GenerateSizeMetrics(module, action string, requestSize, responseSize int64, tags ...string) {
+	specificTags := append([]string{mw.getTag("action", action)}, tags...)
+	if requestSize >= 0 {
+		mw.metrics.Histogram(
+			fmt.Sprintf(mw.metMap["reqSize"], module),
+			float64(requestSize),
+			specificTags,
+			mw.rate,
+		)
+	}
+	if responseSize >= 0 {
+		mw.metrics.Histogram(
+			fmt.Sprintf(mw.metMap["resSize"], module),
+			float64(responseSize),
+			specificTags,
+			mw.rate,
+		)
+	}
+}
@@ -0,0 +1,65 @@
+package bergamot
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+// NewOpenAPICommand returns a command that prints the OpenAPI document
+// of a version of an HTTP server. Should be added to the application root command
+// after registering the routes, e.g. myapp openapi --server api --version 1 --output openapi.json
+func NewOpenAPICommand(app *App) *cobra.Command {
+	var (
+		server  string
+		version int
+		output  string
+	)
+	command := &cobra.Command{
+		Use:   "openapi",
+		Short: "Prints the OpenAPI document of an HTTP server",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			httpServer, err := getHTTPServer(app, server)
+			if err != nil {
+				return err
+			}
+			data, err := httpServer.MarshalOpenAPI(version)
+			if err != nil {
+				return err
+			}
+			if output != "" {
+				return ioutil.WriteFile(output, append(data, '\n'), 0644)
+			}
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), string(data))
+			return err
+		},
+	}
+	command.Flags().StringVar(&server, "server", "", "name of the HTTP server, can be omitted if there is only one")
+	command.Flags().IntVar(&version, "version", 1, "API version")
+	command.Flags().StringVarP(&output, "output", "o", "", "file to write the document to, defaults to stdout")
+	return command
+}
+
+// getHTTPServer returns the HTTP server with the given name
+// or the only HTTP server if the name is empty
+func getHTTPServer(app *App, name string) (*HTTPServer, error) {
+	if name != "" {
+		if server, ok := app.Servers[name].(*HTTPServer); ok {
+			return server, nil
+		}
+		return nil, fmt.Errorf("HTTP server %q not found", name)
+	}
+	var names []string
+	for name, server := range app.Servers {
+		if _, ok := server.(*HTTPServer); ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) != 1 {
+		sort.Strings(names)
+		return nil, fmt.Errorf("expected one HTTP server, use --server to select one of: %v", names)
+	}
+	return app.Servers[names[0]].(*HTTPServer), nil
+}
@@ -0,0 +1,36 @@
+package bergamot_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/alauda/bergamot"
+	"github.com/alauda/bergamot/http"
+	"github.com/alauda/bergamot/http/mux"
+	"github.com/alauda/bergamot/log"
+)
+
+func TestOpenAPICommand(t *testing.T) {
+	server := http.NewServer(http.Config{Component: "test"}, log.EmptyLogger{}).Init()
+	server.AddVersionEndpointFunc(1, "/items", func(router mux.Router, server *http.Server) {
+		router.Get("/:id", func(ctx mux.Context) {})
+	})
+	app := &bergamot.App{Servers: map[string]bergamot.Server{"api": &bergamot.HTTPServer{Server: server}}}
+
+	var output bytes.Buffer
+	command := bergamot.NewOpenAPICommand(app)
+	command.SetOutput(&output)
+	command.SetArgs([]string{"--version", "1"})
+	if err := command.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output.String(), `"/v1/items/{id}"`) {
+		t.Errorf("expected item path in document: %s", output.String())
+	}
+
+	command.SetArgs([]string{"--server", "missing"})
+	if err := command.Execute(); err == nil {
+		t.Errorf("expected error for missing server")
+	}
+}
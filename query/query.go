@@ -1,5 +1,7 @@
 package query
 
+import "math"
+
 // Query used for querying resource database
 type Query map[string]interface{}
 
@@ -17,6 +19,7 @@ func (q Query) Add(key string, value interface{}) Query {
 const (
 	orderByKey = "__order_by__"
 	paramsKey  = "__params__"
+	pagingKey  = "__paging__"
 )
 
 // OrderBy adds order by
@@ -27,7 +30,7 @@ func (q Query) OrderBy(key string, ascending bool) Query {
 
 // GetFields return fields
 func (q Query) GetFields() Query {
-	return FilterKey(q, orderByKey, paramsKey)
+	return FilterKey(q, orderByKey, paramsKey, pagingKey)
 }
 
 // GetOrderBy returns key order by, ascending, and ok
@@ -42,6 +45,21 @@ func (q Query) GetOrderBy() (key string, ascending bool, ok bool) {
 	return
 }
 
+// Paginate adds the requested page
+func (q Query) Paginate(paging Paging) Query {
+	q[pagingKey] = paging
+	return q
+}
+
+// GetPaging returns the requested page and ok
+// ok will be false if the query is not paginated
+func (q Query) GetPaging() (paging Paging, ok bool) {
+	if q != nil {
+		paging, ok = q[pagingKey].(Paging)
+	}
+	return
+}
+
 // GetParams retrieves parameters, and ok
 // ok means it exists otherwise returns false
 // if not existing Parameters will be nil
@@ -99,6 +117,32 @@ func (p Parameters) Add(key string, val interface{}) Parameters {
 	return p
 }
 
+// Paging page requested by the client
+// Page is 0 when using cursor paging
+type Paging struct {
+	Page     int
+	PageSize int
+	// Cursor position to continue from, empty for the first page
+	Cursor string
+}
+
+// Offset returns the number of items before the page
+// offsets larger than math.MaxInt are clamped
+func (p Paging) Offset() int {
+	if p.Page < 1 || p.PageSize < 1 {
+		return 0
+	}
+	if p.Page-1 > math.MaxInt/p.PageSize {
+		return math.MaxInt
+	}
+	return (p.Page - 1) * p.PageSize
+}
+
+// Limit returns the maximum number of items in the page
+func (p Paging) Limit() int {
+	return p.PageSize
+}
+
 // Ordering sorting class
 type Ordering struct {
 	Key       string
@@ -1,6 +1,7 @@
 package query
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -79,9 +80,40 @@ func TestQuery(t *testing.T) {
 				"c": []string{"a", "b", "c"},
 			},
 		},
+		{
+			"paging is not a field",
+			func() Query {
+				return New().
+					Add("a", true).
+					Paginate(Paging{Page: 2, PageSize: 10}).
+					GetFields()
+			},
+			Query{
+				"a": true,
+			},
+		},
 	}
 
 	for _, test := range testTable {
 		assert.EqualValues(test.Expected, test.Prepare(), test.TestName)
 	}
 }
+
+func TestPagingOffset(t *testing.T) {
+	assert := assert.New(t)
+
+	testTable := []struct {
+		TestName string
+		Paging   Paging
+		Expected int
+	}{
+		{"first page", Paging{Page: 1, PageSize: 10}, 0},
+		{"third page", Paging{Page: 3, PageSize: 10}, 20},
+		{"cursor paging", Paging{PageSize: 10, Cursor: "a"}, 0},
+		{"overflow", Paging{Page: math.MaxInt, PageSize: 10}, math.MaxInt},
+	}
+
+	for _, test := range testTable {
+		assert.Equal(test.Expected, test.Paging.Offset(), test.TestName)
+	}
+}
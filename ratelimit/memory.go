@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"container/list"
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultMaxKeys maximum number of keys tracked by a MemoryLimiter by default
+const DefaultMaxKeys = 100000
+
+// MemoryLimiter in-process token bucket limiter
+// each key has a bucket of limit tokens refilled over the window
+type MemoryLimiter struct {
+	limit   int
+	window  time.Duration
+	rate    float64 // tokens per nanosecond
+	maxKeys int
+	now     func() time.Time
+
+	lock    sync.Mutex
+	buckets map[string]*list.Element
+	// used buckets ordered by last use, most recent first
+	used *list.List
+}
+
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// NewMemoryLimiter constructor function for MemoryLimiter
+// allows bursts of up to limit requests and limit requests per window
+// tracking up to DefaultMaxKeys keys
+func NewMemoryLimiter(limit int, window time.Duration) *MemoryLimiter {
+	if limit < 1 {
+		limit = 1
+	}
+	if window <= 0 {
+		window = time.Second
+	}
+	return &MemoryLimiter{
+		limit:   limit,
+		window:  window,
+		rate:    float64(limit) / float64(window),
+		maxKeys: DefaultMaxKeys,
+		now:     time.Now,
+		buckets: map[string]*list.Element{},
+		used:    list.New(),
+	}
+}
+
+// SetMaxKeys sets the maximum number of keys tracked
+// the least recently used key is evicted when a new key is over the maximum
+func (m *MemoryLimiter) SetMaxKeys(max int) *MemoryLimiter {
+	if max < 1 {
+		max = 1
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.maxKeys = max
+	for len(m.buckets) > m.maxKeys {
+		m.remove(m.used.Back())
+	}
+	return m
+}
+
+// Allow consumes a token from the bucket of the key
+func (m *MemoryLimiter) Allow(key string) (Result, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+	elem, ok := m.buckets[key]
+	if ok {
+		m.used.MoveToFront(elem)
+	} else {
+		if len(m.buckets) >= m.maxKeys {
+			m.remove(m.used.Back())
+		}
+		elem = m.used.PushFront(&bucket{key: key, tokens: float64(m.limit), last: now})
+		m.buckets[key] = elem
+	}
+	b := elem.Value.(*bucket)
+	b.tokens = math.Min(float64(m.limit), b.tokens+float64(now.Sub(b.last))*m.rate)
+	b.last = now
+
+	result := Result{Limit: m.limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = m.duration(1 - b.tokens)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = m.duration(float64(m.limit) - b.tokens)
+	return result, nil
+}
+
+// duration returns the time needed to refill the tokens
+func (m *MemoryLimiter) duration(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens / m.rate))
+}
+
+// sweep removes the buckets not used for a window
+// they are full again so removing them does not change any limit
+func (m *MemoryLimiter) sweep(now time.Time) {
+	for elem := m.used.Back(); elem != nil && now.Sub(elem.Value.(*bucket).last) >= m.window; elem = m.used.Back() {
+		m.remove(elem)
+	}
+}
+
+func (m *MemoryLimiter) remove(elem *list.Element) {
+	delete(m.buckets, m.used.Remove(elem).(*bucket).key)
+}
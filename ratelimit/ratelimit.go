@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alauda/bergamot/auth"
+	"github.com/alauda/bergamot/errors"
+)
+
+// Source source used in rate limit errors
+const Source = "ratelimit"
+
+// Result state of the limit of a key after a request
+type Result struct {
+	// Allowed true if the request can proceed
+	Allowed bool
+	// Limit maximum number of requests in a window
+	Limit int
+	// Remaining requests left in the current window
+	Remaining int
+	// Reset time until the limit is fully restored
+	Reset time.Duration
+	// RetryAfter time to wait before retrying, zero if allowed
+	RetryAfter time.Duration
+}
+
+// Limiter counts requests by key
+type Limiter interface {
+	// Allow consumes a request for the key and returns the resulting state
+	Allow(key string) (Result, error)
+}
+
+// LimiterFunc function that implements the Limiter interface
+type LimiterFunc func(key string) (Result, error)
+
+// Allow calls the function
+func (f LimiterFunc) Allow(key string) (Result, error) {
+	return f(key)
+}
+
+// NewTooManyRequestsError returns a too_many_requests error for the result
+func NewTooManyRequestsError(result Result) *errors.AlaudaError {
+	return errors.New(Source, errors.ErrorCodeTooManyRequests).
+		SetMessage("Rate limit exceeded, retry in %v", ceilSeconds(result.RetryAfter))
+}
+
+// Seconds returns the duration in whole seconds rounding up
+// as used by the Retry-After and X-RateLimit-Reset headers
+func Seconds(d time.Duration) int64 {
+	return int64(ceilSeconds(d) / time.Second)
+}
+
+func ceilSeconds(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return ((d + time.Second - 1) / time.Second) * time.Second
+}
+
+// UserKey returns the key of a user stored in the context
+// using the name for auth users and fmt.Stringer implementations,
+// returns an empty string if there is no user
+func UserKey(user interface{}) string {
+	switch val := user.(type) {
+	case nil:
+		return ""
+	case *auth.User:
+		if val == nil {
+			return ""
+		}
+		return val.Name
+	case string:
+		return val
+	case fmt.Stringer:
+		return val.String()
+	}
+	return fmt.Sprint(user)
+}
@@ -0,0 +1,144 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alauda/bergamot/ratelimit"
+
+	aredis "github.com/alauda/go-redis-client"
+	"github.com/go-redis/redis"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	type TestCase struct {
+		Name      string
+		Key       string
+		Allowed   bool
+		Remaining int
+	}
+
+	table := []TestCase{
+		{"first", "a", true, 1},
+		{"second", "a", true, 0},
+		{"over the limit", "a", false, 0},
+		{"other key", "b", true, 1},
+	}
+
+	limiter := ratelimit.NewMemoryLimiter(2, time.Hour)
+	for i, test := range table {
+		result, err := limiter.Allow(test.Key)
+		if err != nil {
+			t.Fatalf("%d - %s -- unexpected error: %v", i, test.Name, err)
+		}
+		if result.Allowed != test.Allowed || result.Remaining != test.Remaining || result.Limit != 2 {
+			t.Errorf("%d - %s -- unexpected result: %+v", i, test.Name, result)
+		}
+		if !result.Allowed && (result.RetryAfter <= 0 || result.RetryAfter > time.Hour) {
+			t.Errorf("%d - %s -- unexpected retry after: %v", i, test.Name, result.RetryAfter)
+		}
+	}
+}
+
+func TestMemoryLimiterMaxKeys(t *testing.T) {
+	limiter := ratelimit.NewMemoryLimiter(1, time.Hour).SetMaxKeys(2)
+	for _, key := range []string{"a", "a", "b", "c"} {
+		limiter.Allow(key)
+	}
+	// a is the least recently used key and was evicted by c
+	if result, _ := limiter.Allow("a"); !result.Allowed {
+		t.Errorf("expected evicted key to be allowed got: %+v", result)
+	}
+	if result, _ := limiter.Allow("c"); result.Allowed {
+		t.Errorf("expected tracked key to be limited got: %+v", result)
+	}
+}
+
+// fakeRedis counts keys in memory using the commands of RedisLimiter
+type fakeRedis struct {
+	aredis.Commander
+	counts map[string]int64
+}
+
+func (f *fakeRedis) Incr(key string) *redis.IntCmd {
+	f.counts[key]++
+	return redis.NewIntResult(f.counts[key], nil)
+}
+
+func (f *fakeRedis) PTTL(key string) *redis.DurationCmd {
+	return redis.NewDurationResult(-1, nil)
+}
+
+func (f *fakeRedis) PExpire(key string, expiration time.Duration) *redis.BoolCmd {
+	return redis.NewBoolResult(true, nil)
+}
+
+// scriptRedis fakeRedis that also runs the limiter script
+type scriptRedis struct {
+	*fakeRedis
+}
+
+func (s scriptRedis) Eval(script string, keys []string, args ...interface{}) *redis.Cmd {
+	s.counts[keys[0]]++
+	return redis.NewCmdResult([]interface{}{s.counts[keys[0]], args[0]}, nil)
+}
+
+// prefixClient adds a key prefix like the alauda redis client
+// and returns the underlying client
+type prefixClient struct {
+	aredis.Commander
+	client aredis.Commander
+}
+
+func (p prefixClient) Incr(key string) *redis.IntCmd {
+	return p.client.Incr("client:" + key)
+}
+
+func (p prefixClient) GetClient() aredis.Commander {
+	return p.client
+}
+
+type fakeCache struct {
+	writer aredis.Commander
+}
+
+func (f fakeCache) Reader() aredis.Commander { return f.writer }
+func (f fakeCache) Writer() aredis.Commander { return f.writer }
+
+func TestRedisLimiter(t *testing.T) {
+	type TestCase struct {
+		Name   string
+		Client func(counts map[string]int64) aredis.Commander
+	}
+
+	table := []TestCase{
+		{"incr", func(counts map[string]int64) aredis.Commander {
+			return &fakeRedis{counts: counts}
+		}},
+		{"script", func(counts map[string]int64) aredis.Commander {
+			return scriptRedis{&fakeRedis{counts: counts}}
+		}},
+		{"incr with client prefix", func(counts map[string]int64) aredis.Commander {
+			return prefixClient{client: &fakeRedis{counts: counts}}
+		}},
+		{"script with client prefix", func(counts map[string]int64) aredis.Commander {
+			return prefixClient{client: scriptRedis{&fakeRedis{counts: counts}}}
+		}},
+	}
+
+	for i, test := range table {
+		counts := map[string]int64{}
+		limiter := ratelimit.NewRedisLimiter(fakeCache{test.Client(counts)}, "rl:", 1, time.Minute)
+		first, err := limiter.Allow("a")
+		if err != nil {
+			t.Fatalf("%d - %s -- unexpected error: %v", i, test.Name, err)
+		}
+		second, _ := limiter.Allow("a")
+		if !first.Allowed || second.Allowed || second.RetryAfter != time.Minute {
+			t.Errorf("%d - %s -- unexpected results: %+v %+v", i, test.Name, first, second)
+		}
+		if len(counts) != 1 || counts["rl:a"] != 2 {
+			t.Errorf("%d - %s -- expected counter rl:a got: %v", i, test.Name, counts)
+		}
+	}
+}
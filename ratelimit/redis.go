@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/alauda/bergamot/cache"
+
+	aredis "github.com/alauda/go-redis-client"
+	"github.com/go-redis/redis"
+)
+
+// script increments the counter of the window and returns it with its ttl
+const script = `
+local count = redis.call("INCR", KEYS[1])
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`
+
+// scripter clients that can run Lua scripts
+type scripter interface {
+	Eval(script string, keys []string, args ...interface{}) *redis.Cmd
+}
+
+// clientGetter implemented by the alauda redis client
+// to return the underlying go-redis client
+type clientGetter interface {
+	GetClient() aredis.Commander
+}
+
+// RedisLimiter distributed fixed window limiter
+// shares the counters between all the instances using the cache writer
+type RedisLimiter struct {
+	cache  cache.Cache
+	prefix string
+	limit  int
+	window time.Duration
+}
+
+// NewRedisLimiter constructor function for RedisLimiter
+// allows limit requests per window, keys are stored using the prefix
+func NewRedisLimiter(c cache.Cache, prefix string, limit int, window time.Duration) *RedisLimiter {
+	if limit < 1 {
+		limit = 1
+	}
+	if window < time.Millisecond {
+		window = time.Second
+	}
+	return &RedisLimiter{
+		cache:  c,
+		prefix: prefix,
+		limit:  limit,
+		window: window,
+	}
+}
+
+// Allow increments the counter of the key in the current window
+// using a Lua script when supported by the client or INCR and PEXPIRE otherwise.
+// Both run on the underlying client of the alauda redis client so the keys
+// only have the limiter prefix and never the client key prefix
+func (r *RedisLimiter) Allow(key string) (Result, error) {
+	count, ttl, err := r.incr(r.prefix + key)
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{
+		Allowed: count <= int64(r.limit),
+		Limit:   r.limit,
+		Reset:   ttl,
+	}
+	if result.Allowed {
+		result.Remaining = r.limit - int(count)
+	} else {
+		result.RetryAfter = ttl
+	}
+	return result, nil
+}
+
+// client returns the writer or its underlying client if available
+func (r *RedisLimiter) client() aredis.Commander {
+	writer := r.cache.Writer()
+	if getter, ok := writer.(clientGetter); ok {
+		return getter.GetClient()
+	}
+	return writer
+}
+
+func (r *RedisLimiter) incr(key string) (int64, time.Duration, error) {
+	writer := r.client()
+	if client, ok := writer.(scripter); ok {
+		return r.eval(client, key)
+	}
+
+	count, err := writer.Incr(key).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+	ttl, err := writer.PTTL(key).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+	if ttl < 0 {
+		if err = writer.PExpire(key, r.window).Err(); err != nil {
+			return 0, 0, err
+		}
+		ttl = r.window
+	}
+	return count, ttl, nil
+}
+
+func (r *RedisLimiter) eval(client scripter, key string) (int64, time.Duration, error) {
+	values, err := client.Eval(script, []string{key}, int64(r.window/time.Millisecond)).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+	reply, ok := values.([]interface{})
+	if !ok || len(reply) != 2 {
+		return 0, 0, redis.Nil
+	}
+	count, _ := reply[0].(int64)
+	ttl, _ := reply[1].(int64)
+	return count, time.Duration(ttl) * time.Millisecond, nil
+}
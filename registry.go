@@ -0,0 +1,103 @@
+package bergamot
+
+import (
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// DatasourceFactory builds a datasource using the configuration sub-tree of its entry
+// name is the entry name inside the datasources section
+type DatasourceFactory func(name string, config *viper.Viper) (Datasource, error)
+
+// ServerFactory builds a server using the configuration sub-tree of its entry
+// name is the entry name inside the servers section
+type ServerFactory func(name string, config *viper.Viper) (Server, error)
+
+type kindRegistry struct {
+	datasources       map[string]DatasourceFactory
+	datasourceSchemas map[string][]Field
+	servers           map[string]ServerFactory
+	serverSchemas     map[string][]Field
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     = kindRegistry{
+		datasources:       map[string]DatasourceFactory{},
+		datasourceSchemas: map[string][]Field{},
+		servers:           map[string]ServerFactory{},
+		serverSchemas:     map[string][]Field{},
+	}
+)
+
+// RegisterDatasource registers a factory for a datasource type
+// fields declare the schema used to validate its configuration,
+// types registered without fields will accept any key.
+// Registering an already existing type will replace its factory
+func RegisterDatasource(kind string, factory DatasourceFactory, fields ...Field) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry.datasources[kind] = factory
+	registry.datasourceSchemas[kind] = fields
+}
+
+// RegisterServer registers a factory for a server type
+// fields declare the schema used to validate its configuration,
+// types registered without fields will accept any key.
+// Registering an already existing type will replace its factory
+func RegisterServer(kind string, factory ServerFactory, fields ...Field) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry.servers[kind] = factory
+	registry.serverSchemas[kind] = fields
+}
+
+// GetDatasourceFactory returns the registered factory for a datasource type
+func GetDatasourceFactory(kind string) (factory DatasourceFactory, ok bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	factory, ok = registry.datasources[kind]
+	return
+}
+
+// GetServerFactory returns the registered factory for a server type
+func GetServerFactory(kind string) (factory ServerFactory, ok bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	factory, ok = registry.servers[kind]
+	return
+}
+
+// GetDatasourceSchema returns the declared fields for a datasource type
+// fields will be nil if the type was registered without a schema
+func GetDatasourceSchema(kind string) (fields []Field, ok bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	fields, ok = registry.datasourceSchemas[kind]
+	return
+}
+
+// GetServerSchema returns the declared fields for a server type
+// fields will be nil if the type was registered without a schema
+func GetServerSchema(kind string) (fields []Field, ok bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	fields, ok = registry.serverSchemas[kind]
+	return
+}
+
+// getSchemas returns a copy of all the datasource and server schemas by type
+func getSchemas() (datasources, servers map[string][]Field) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	datasources = make(map[string][]Field, len(registry.datasourceSchemas))
+	for k, v := range registry.datasourceSchemas {
+		datasources[k] = v
+	}
+	servers = make(map[string][]Field, len(registry.serverSchemas))
+	for k, v := range registry.serverSchemas {
+		servers[k] = v
+	}
+	return
+}
@@ -0,0 +1,229 @@
+package bergamot
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/alauda/bergamot/auth"
+	"github.com/alauda/bergamot/log"
+	"github.com/alauda/bergamot/loggo"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// ChangeCallback function called for each changed setting when reloading the configuration
+// key is the full path of the setting like routes.test.path
+type ChangeCallback func(key string, oldValue, newValue interface{})
+
+// OnConfigChange adds a callback for changed settings when reloading the configuration.
+// Only settings that are not applied by the app will be given
+func (a *App) OnConfigChange(callback ChangeCallback) *App {
+	a.reloadLock.Lock()
+	defer a.reloadLock.Unlock()
+	a.callbacks = append(a.callbacks, callback)
+	return a
+}
+
+// EnableReload enables the reload mode: changes in the configuration file
+// or a SIGHUP received while running will reload the configuration.
+// Settings safe to change are applied live: log_level, log_config,
+// servers allowed_origins, datasources sample_rate and shutdown_timeout.
+// Other settings of datasources and servers require a restart and will only log a warning,
+// any other setting will be given to the OnConfigChange callbacks
+func (a *App) EnableReload() *App {
+	a.reloadLock.Lock()
+	a.reload = true
+	a.reloadLock.Unlock()
+	if err := a.watchConfig(); err != nil {
+		log.GetSafe(a.log).Errorf("Failed watching configuration: %v", err)
+	}
+	return a
+}
+
+// watchConfig reloads the configuration when its file is written.
+// viper's WatchConfig is not used because it reads the configuration
+// from its own goroutine without any lock
+func (a *App) watchConfig() error {
+	file := a.Config.ConfigFileUsed()
+	if file == "" {
+		return nil
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	file = filepath.Clean(file)
+	// watching the directory picks up renames and atomic saves
+	if err = watcher.Add(filepath.Dir(file)); err != nil {
+		watcher.Close()
+		return err
+	}
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) == file && event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+					a.Reload()
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.GetSafe(a.log).Errorf("Failed watching configuration: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Reload reads the configuration again and applies the changes
+// reading and applying are serialised using the reload lock
+func (a *App) Reload() error {
+	a.reloadLock.Lock()
+	defer a.reloadLock.Unlock()
+	if err := a.Config.ReadInConfig(); err != nil {
+		log.GetSafe(a.log).Errorf("Failed reading configuration: %v", err)
+		return err
+	}
+	return a.applyChanges()
+}
+
+// applyChanges compares the current configuration with the last applied one
+// invalid configurations are ignored, the reload lock must be held
+func (a *App) applyChanges() error {
+	logger := log.GetSafe(a.log)
+	if err := Validate(a.Config); err != nil {
+		logger.Errorf("Invalid configuration, changes were not applied: %v", err)
+		return err
+	}
+	settings := getFlatSettings(a.Config)
+	keys := make([]string, 0, len(settings))
+	for k := range settings {
+		keys = append(keys, k)
+	}
+	for k := range a.settings {
+		if _, ok := settings[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !reflect.DeepEqual(a.settings[k], settings[k]) {
+			a.applyChange(k, a.settings[k], settings[k])
+		}
+	}
+	a.settings = settings
+	return nil
+}
+
+// applyChange applies a setting live when possible
+// otherwise warns or calls the callbacks
+func (a *App) applyChange(key string, oldValue, newValue interface{}) {
+	logger := log.GetSafe(a.log)
+	parts := strings.SplitN(key, ".", 3)
+	switch {
+	case key == "log_level" || key == "log_config":
+		if newValue == nil || newValue == "" {
+			// removed settings go back to the default levels
+			loggo.DefaultContext().ResetLoggerLevels()
+		}
+		a.applyLogging()
+	case key == "shutdown_timeout":
+		a.ShutdownTimeout = cast.ToDuration(newValue)
+	case len(parts) == 3 && parts[0] == "servers" && parts[2] == "allowed_origins":
+		if server, ok := a.Servers[parts[1]].(*HTTPServer); ok {
+			value, err := a.resolveSetting(parts[0], parts[1], parts[2])
+			if err != nil {
+				logger.Errorf("Invalid %s: %v", key, err)
+				return
+			}
+			server.SetAllowedOrigins(cast.ToStringSlice(value))
+			return
+		}
+		logger.Warningf("Setting %s changed but requires a restart to be applied", key)
+	case len(parts) == 3 && parts[0] == "servers" && parts[2] == "policies":
+		if server, ok := a.Servers[parts[1]].(*HTTPServer); ok {
+			value, err := a.resolveSetting(parts[0], parts[1], parts[2])
+			if err != nil {
+				logger.Errorf("Invalid %s: %v", key, err)
+				return
+			}
+			policies, err := auth.ParsePolicies(value)
+			if err != nil {
+				logger.Errorf("Invalid %s: %v", key, err)
+				return
+			}
+			server.SetPolicies(policies)
+			return
+		}
+		logger.Warningf("Setting %s changed but requires a restart to be applied", key)
+	case len(parts) == 3 && parts[0] == "datasources" && parts[2] == "sample_rate":
+		if metrics, ok := a.Datasources[parts[1]].(*MetricsDatasource); ok {
+			value, err := a.resolveSetting(parts[0], parts[1], parts[2])
+			if err != nil {
+				logger.Errorf("Invalid %s: %v", key, err)
+				return
+			}
+			metrics.SetRate(cast.ToFloat64(value))
+			return
+		}
+		logger.Warningf("Setting %s changed but requires a restart to be applied", key)
+	case parts[0] == "datasources" || parts[0] == "servers" || key == "component" || key == "diagnose_path":
+		logger.Warningf("Setting %s changed but requires a restart to be applied", key)
+	default:
+		for _, callback := range a.callbacks {
+			callback(key, oldValue, newValue)
+		}
+	}
+}
+
+// resolveSetting returns a setting of a datasource or server entry
+// resolved the same way as when building the entry:
+// ${ENV_VAR} interpolation and environment overrides
+func (a *App) resolveSetting(section, name, key string) (interface{}, error) {
+	path := section + "." + name
+	values := a.Config.GetStringMap(path)
+	if len(values) == 0 {
+		return nil, nil
+	}
+	getSchema := GetDatasourceSchema
+	if section == "servers" {
+		getSchema = GetServerSchema
+	}
+	values, _, err := prepareEntry(path, name, values, getSchema)
+	if err != nil {
+		return nil, err
+	}
+	return values[key], nil
+}
+
+// applyLogging configures the log levels using log_level and log_config
+func (a *App) applyLogging() {
+	if name := a.Config.GetString("log_level"); name != "" {
+		if level, err := log.ParseLevel(name); err == nil {
+			log.SetLevel(level)
+		}
+	}
+	if config := a.Config.GetString("log_config"); config != "" {
+		if err := loggo.ConfigureLoggers(config); err != nil {
+			log.GetSafe(a.log).Errorf("Invalid log_config %q: %v", config, err)
+		}
+	}
+}
+
+// getFlatSettings returns all the settings using the full key path
+func getFlatSettings(config *viper.Viper) map[string]interface{} {
+	settings := map[string]interface{}{}
+	for _, k := range config.AllKeys() {
+		settings[k] = config.Get(k)
+	}
+	return settings
+}
@@ -0,0 +1,119 @@
+package bergamot_test
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alauda/bergamot"
+	"github.com/alauda/bergamot/loggo"
+	"github.com/spf13/viper"
+)
+
+func TestAppReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bergamot")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "config.yaml")
+	write := func(content string) {
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("unexpected error writing config: %v", err)
+		}
+	}
+
+	write("component: comp\nlog_level: error\nshutdown_timeout: 10s\nroutes:\n  test: /test\n")
+	config := viper.New()
+	config.SetConfigFile(file)
+	app, err := bergamot.New(config, bergamot.DefaultParser{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	changes := map[string][2]interface{}{}
+	app.OnConfigChange(func(key string, oldValue, newValue interface{}) {
+		changes[key] = [2]interface{}{oldValue, newValue}
+	})
+
+	// invalid configuration is not applied
+	write("component: comp\nlog_level: error\nshutdown_timeout: soon\nroutes:\n  test: /other\n")
+	if err = app.Reload(); err == nil {
+		t.Errorf("expected validation error for invalid configuration")
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected no changes for invalid configuration got: %v", changes)
+	}
+
+	write("component: other\nshutdown_timeout: 20s\nroutes:\n  test: /other\n")
+	if err = app.Reload(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if app.ShutdownTimeout != 20*time.Second {
+		t.Errorf("expected shutdown timeout to be applied live got: %v", app.ShutdownTimeout)
+	}
+	if level := loggo.GetLogger("").LogLevel(); level != loggo.WARNING {
+		t.Errorf("expected removed log_level to restore the default level got: %v", level)
+	}
+	if len(changes) != 1 || changes["routes.test"] != [2]interface{}{"/test", "/other"} {
+		t.Errorf("expected only routes.test change got: %v", changes)
+	}
+}
+
+func TestAppReloadResolvesEntries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bergamot")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "config.yaml")
+	write := func(content string) {
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("unexpected error writing config: %v", err)
+		}
+	}
+
+	os.Setenv("BERGAMOT_DATASOURCES_STATSD_SAMPLE_RATE", "0.5")
+	os.Setenv("RELOAD_ORIGIN", "https://env.example.com")
+	defer os.Unsetenv("BERGAMOT_DATASOURCES_STATSD_SAMPLE_RATE")
+	defer os.Unsetenv("RELOAD_ORIGIN")
+
+	statsd := "datasources:\n  statsd:\n    host: localhost\n    port: 8125\n    enabled: false\n"
+	write("log_level: error\n" + statsd + "    sample_rate: 1\n" +
+		"servers:\n  http:\n    port: 80\n    allowed_origins: [https://a.example.com]\n")
+	config := viper.New()
+	config.SetConfigFile(file)
+	app, err := bergamot.New(config, bergamot.DefaultParser{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	write("log_level: error\n" + statsd + "    sample_rate: 0.8\n" +
+		"servers:\n  http:\n    port: 80\n    allowed_origins: [\"${RELOAD_ORIGIN}\"]\n")
+	if err = app.Reload(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rate := app.Datasources["statsd"].(*bergamot.MetricsDatasource).Rate(); rate != 0.5 {
+		t.Errorf("expected overridden sample rate 0.5 got: %v", rate)
+	}
+
+	server := app.Servers["http"].(*bergamot.HTTPServer)
+	for _, test := range []struct {
+		Origin   string
+		Expected string
+	}{
+		{"https://env.example.com", "https://env.example.com"},
+		{"https://a.example.com", ""},
+	} {
+		request := httptest.NewRequest("GET", "/", nil)
+		request.Header.Set("Origin", test.Origin)
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		if origin := recorder.Header().Get("Access-Control-Allow-Origin"); origin != test.Expected {
+			t.Errorf("%s -- expected allowed origin %q got %q", test.Origin, test.Expected, origin)
+		}
+	}
+}
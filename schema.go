@@ -0,0 +1,342 @@
+package bergamot
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/alauda/bergamot/log"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// FieldType type of a configuration field
+type FieldType string
+
+const (
+	// TypeString string values
+	TypeString FieldType = "string"
+	// TypeInt integer values
+	TypeInt FieldType = "int"
+	// TypeFloat float values
+	TypeFloat FieldType = "float"
+	// TypeBool boolean values
+	TypeBool FieldType = "bool"
+	// TypeDuration duration values like 10s or 1m30s
+	TypeDuration FieldType = "duration"
+	// TypeList list of strings
+	TypeList FieldType = "list"
+	// TypeMap map with any keys or with the keys declared in Fields
+	TypeMap FieldType = "map"
+)
+
+// Field declares a configuration key of a datasource or server type
+type Field struct {
+	Name        string
+	Type        FieldType
+	Required    bool
+	Description string
+	// Secret string fields that can be read from a file
+	// using the field name with a _file suffix, e.g. password_file.
+	// For TypeMap fields any key can be read from a file, e.g. alice_file
+	Secret bool
+	// Fields nested fields for TypeMap
+	// when empty any key will be accepted
+	Fields []Field
+}
+
+// typeKey key used to declare the type of an entry
+const typeKey = "type"
+
+var appFields = []Field{
+	{Name: "component", Type: TypeString, Description: "component name"},
+	{Name: "shutdown_timeout", Type: TypeDuration, Description: "deadline to drain servers when shutting down"},
+	{Name: "diagnose_path", Type: TypeString, Description: "path of the diagnose endpoint on all servers, empty to disable"},
+	{Name: "log_level", Type: TypeString, Description: "root log level: trace, debug, info or error"},
+	{Name: "log_config", Type: TypeString, Description: "loggo configuration like <root>=INFO;bergamot=DEBUG"},
+	{Name: "datasources", Type: TypeMap, Description: "datasources by name"},
+	{Name: "servers", Type: TypeMap, Description: "servers by name"},
+}
+
+// Validate validates the configuration against the schema declared
+// by each registered datasource and server type.
+// Returns all the problems found as ValidationErrors
+func Validate(config *viper.Viper) error {
+	var errs ValidationErrors
+	settings := config.AllSettings()
+	for _, field := range appFields {
+		if value, ok := settings[field.Name]; ok {
+			errs.Add(validateValue(field.Name, field, value))
+		}
+	}
+	if level := config.GetString("log_level"); level != "" {
+		if _, err := log.ParseLevel(level); err != nil {
+			errs = append(errs, NewValidationError("log_level", "%v", err))
+		}
+	}
+	errs.Add(validateSection(settings, "datasources", GetDatasourceSchema))
+	errs.Add(validateSection(settings, "servers", GetServerSchema))
+	return errs.Err()
+}
+
+// validateSection validates all entries of a datasources or servers section
+func validateSection(settings map[string]interface{}, section string, getSchema func(kind string) ([]Field, bool)) error {
+	var errs ValidationErrors
+	entries, err := cast.ToStringMapE(settings[section])
+	if err != nil || len(entries) == 0 {
+		return nil
+	}
+	for _, name := range sortedKeys(entries) {
+		path := section + "." + name
+		values, err := cast.ToStringMapE(entries[name])
+		if err != nil || values == nil {
+			errs = append(errs, NewValidationError(path, "expected map"))
+			continue
+		}
+		_, _, err = prepareEntry(path, name, values, getSchema)
+		errs.Add(err)
+	}
+	return errs.Err()
+}
+
+// prepareEntry resolves the values of one entry of a section and validates them
+// against the schema of its type. If the type is not set the entry name is used instead.
+// Returns the resolved values and the type of the entry
+func prepareEntry(path, name string, values map[string]interface{}, getSchema func(kind string) ([]Field, bool)) (map[string]interface{}, string, error) {
+	kind := cast.ToString(values[typeKey])
+	if kind == "" {
+		kind = name
+	}
+	fields, ok := getSchema(kind)
+	if !ok {
+		return nil, kind, NewValidationError(path+"."+typeKey, "unknown type %q", kind)
+	}
+	values, err := resolveEntry(path, values, fields)
+	if err != nil {
+		return nil, kind, err
+	}
+	if fields == nil {
+		// types registered without schema
+		return values, kind, nil
+	}
+	return values, kind, validateFields(path, fields, values, typeKey)
+}
+
+// validateFields validates the values against the given fields
+// reports missing required fields and unknown keys
+func validateFields(path string, fields []Field, values map[string]interface{}, allowed ...string) error {
+	var errs ValidationErrors
+	known := make(map[string]struct{}, len(fields)+len(allowed))
+	for _, k := range allowed {
+		known[k] = struct{}{}
+	}
+	for _, field := range fields {
+		known[field.Name] = struct{}{}
+		value, ok := values[field.Name]
+		if !ok || value == nil || value == "" {
+			if field.Required {
+				errs = append(errs, NewValidationError(path+"."+field.Name, "required field is missing"))
+			}
+			continue
+		}
+		errs.Add(validateValue(path+"."+field.Name, field, value))
+	}
+	for _, k := range sortedKeys(values) {
+		if _, ok := known[k]; !ok {
+			errs = append(errs, NewValidationError(path+"."+k, "unknown field"))
+		}
+	}
+	return errs.Err()
+}
+
+// validateValue validates a value is of the field type
+func validateValue(path string, field Field, value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	var valid bool
+	switch field.Type {
+	case TypeString:
+		valid = isScalar(value)
+	case TypeInt:
+		valid = isInt(value)
+	case TypeFloat:
+		_, err := strconv.ParseFloat(cast.ToString(value), 64)
+		valid = isScalar(value) && err == nil
+	case TypeBool:
+		_, err := strconv.ParseBool(cast.ToString(value))
+		valid = isScalar(value) && err == nil
+	case TypeDuration:
+		valid = isDuration(value)
+	case TypeList:
+		kind := reflect.TypeOf(value).Kind()
+		valid = kind == reflect.Slice || kind == reflect.String
+	case TypeMap:
+		values, err := cast.ToStringMapE(value)
+		if err != nil {
+			break
+		}
+		if len(field.Fields) > 0 {
+			return validateFields(path, field.Fields, values)
+		}
+		valid = true
+	default:
+		valid = true
+	}
+	if !valid {
+		return NewValidationError(path, "expected %s", field.Type)
+	}
+	return nil
+}
+
+func isScalar(value interface{}) bool {
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		return false
+	}
+	return true
+}
+
+func isInt(value interface{}) bool {
+	switch val := value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return true
+	case float64:
+		return val == float64(int64(val))
+	case string:
+		_, err := strconv.ParseInt(val, 0, 0)
+		return err == nil
+	}
+	return false
+}
+
+func isDuration(value interface{}) bool {
+	switch val := value.(type) {
+	case time.Duration:
+		return true
+	case string:
+		if _, err := time.ParseDuration(val); err == nil {
+			return true
+		}
+	}
+	return isInt(value)
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// JSONSchema generates a JSON Schema document describing all known keys
+// of the configuration including all registered datasource and server types
+func JSONSchema() ([]byte, error) {
+	datasources, servers := getSchemas()
+	definitions := map[string]interface{}{}
+	properties := getJSONProperties(appFields)
+	properties["datasources"] = getJSONSection("datasource", datasources, definitions)
+	properties["servers"] = getJSONSection("server", servers, definitions)
+
+	return json.MarshalIndent(map[string]interface{}{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"title":       "bergamot configuration",
+		"type":        "object",
+		"properties":  properties,
+		"definitions": definitions,
+	}, "", "  ")
+}
+
+// getJSONSection adds a definition for each kind and returns a section
+// property that accepts any of them
+func getJSONSection(prefix string, schemas map[string][]Field, definitions map[string]interface{}) map[string]interface{} {
+	kinds := make([]string, 0, len(schemas))
+	for kind := range schemas {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	refs := make([]interface{}, 0, len(kinds))
+	for _, kind := range kinds {
+		name := fmt.Sprintf("%s.%s", prefix, kind)
+		definition := map[string]interface{}{"type": "object"}
+		if fields := schemas[kind]; fields != nil {
+			definition = getJSONObject(fields)
+			definition["additionalProperties"] = false
+			definition["properties"].(map[string]interface{})[typeKey] = map[string]interface{}{
+				"type": "string",
+				"enum": []string{kind},
+			}
+		}
+		definitions[name] = definition
+		refs = append(refs, map[string]string{"$ref": "#/definitions/" + name})
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": map[string]interface{}{"anyOf": refs},
+	}
+}
+
+func getJSONObject(fields []Field) map[string]interface{} {
+	object := map[string]interface{}{
+		"type":       "object",
+		"properties": getJSONProperties(fields),
+	}
+	var required []string
+	for _, f := range fields {
+		if f.Required {
+			required = append(required, f.Name)
+		}
+	}
+	if len(required) > 0 {
+		object["required"] = required
+	}
+	return object
+}
+
+func getJSONProperties(fields []Field) map[string]interface{} {
+	properties := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		properties[f.Name] = getJSONProperty(f)
+	}
+	return properties
+}
+
+func getJSONProperty(field Field) map[string]interface{} {
+	var property map[string]interface{}
+	switch field.Type {
+	case TypeInt:
+		property = map[string]interface{}{"type": "integer"}
+	case TypeFloat:
+		property = map[string]interface{}{"type": "number"}
+	case TypeBool:
+		property = map[string]interface{}{"type": "boolean"}
+	case TypeDuration:
+		property = map[string]interface{}{
+			"type":    []string{"string", "integer"},
+			"pattern": `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`,
+		}
+	case TypeList:
+		property = map[string]interface{}{
+			"type":  "array",
+			"items": map[string]string{"type": "string"},
+		}
+	case TypeMap:
+		property = map[string]interface{}{"type": "object"}
+		if len(field.Fields) > 0 {
+			property = getJSONObject(field.Fields)
+			property["additionalProperties"] = false
+		}
+	default:
+		property = map[string]interface{}{"type": "string"}
+	}
+	if field.Description != "" {
+		property["description"] = field.Description
+	}
+	return property
+}
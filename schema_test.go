@@ -0,0 +1,88 @@
+package bergamot_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/alauda/bergamot"
+	"github.com/spf13/viper"
+)
+
+func TestValidate(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Config   string
+		Expected []string
+	}
+
+	table := []TestCase{
+		{
+			"valid",
+			"component: comp\nservers:\n  http:\n    port: 80\ndatasources:\n  cache:\n    type: redis\n    host: redis\n    port: \"6379\"\n",
+			nil,
+		},
+		{
+			"wrong types",
+			"shutdown_timeout: soon\ndatasources:\n  mysql:\n    host: db\n    database: db\n    port: abc\n",
+			[]string{"shutdown_timeout: expected duration", "datasources.mysql.port: expected int"},
+		},
+		{
+			"typos and nested fields",
+			"servers:\n  http:\n    prot: 80\ndatasources:\n  redis:\n    host: redis\n    port: 6379\n    writer:\n      host: redis\n",
+			[]string{
+				"datasources.redis.writer.port: required field is missing",
+				"servers.http.port: required field is missing",
+				"servers.http.prot: unknown field",
+			},
+		},
+	}
+
+	for i, test := range table {
+		config := viper.New()
+		config.SetConfigType("yaml")
+		if err := config.ReadConfig(bytes.NewBufferString(test.Config)); err != nil {
+			t.Fatalf("%d - %s -- unexpected error reading config: %v", i, test.Name, err)
+		}
+		err := bergamot.Validate(config)
+		if test.Expected == nil {
+			if err != nil {
+				t.Errorf("%d - %s -- unexpected error: %v", i, test.Name, err)
+			}
+			continue
+		}
+		errs, _ := err.(bergamot.ValidationErrors)
+		if len(errs) != len(test.Expected) {
+			t.Errorf("%d - %s -- expected %d errors got: %v", i, test.Name, len(test.Expected), err)
+			continue
+		}
+		for j, msg := range test.Expected {
+			if errs[j].Error() != msg {
+				t.Errorf("%d - %s -- expected %q got %q", i, test.Name, msg, errs[j].Error())
+			}
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	data, err := bergamot.JSONSchema()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var schema struct {
+		Definitions map[string]struct {
+			Required []string `json:"required"`
+		} `json:"definitions"`
+	}
+	if err = json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	for _, name := range []string{"datasource.mysql", "datasource.redis", "server.http", "server.grpc"} {
+		if _, ok := schema.Definitions[name]; !ok {
+			t.Errorf("expected definition %s in schema", name)
+		}
+	}
+	if required := schema.Definitions["datasource.mysql"].Required; len(required) != 2 {
+		t.Errorf("expected mysql required fields host and database got: %v", required)
+	}
+}
@@ -0,0 +1,179 @@
+package bergamot
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// EnvPrefix prefix of the environment variables that override
+// datasource and server settings, e.g. BERGAMOT_DATASOURCES_MYSQL_PASSWORD
+const EnvPrefix = "BERGAMOT"
+
+const (
+	// fileSuffix suffix of the keys used to read secret fields from files
+	fileSuffix = "_file"
+	// defaultSecret secret field for types registered without schema
+	defaultSecret = "password"
+	redacted      = "******"
+)
+
+var (
+	envPattern   = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+	envReplacer  = strings.NewReplacer(".", "_", "-", "_")
+	secretTokens = []string{"password", "secret", "token", "basic_users"}
+)
+
+// GetEnvKey returns the environment variable name used to override a setting
+// e.g. datasources.mysql.password will return BERGAMOT_DATASOURCES_MYSQL_PASSWORD
+func GetEnvKey(path string) string {
+	return EnvPrefix + "_" + strings.ToUpper(envReplacer.Replace(path))
+}
+
+// resolveEntry returns a copy of the values of an entry with:
+// ${ENV_VAR} interpolated in all string values,
+// overrides from environment variables for all the fields and keys,
+// and secret fields read from their _file keys
+func resolveEntry(path string, values map[string]interface{}, fields []Field) (map[string]interface{}, error) {
+	var errs ValidationErrors
+	resolved, _ := interpolate(path, values, &errs).(map[string]interface{})
+	resolveFields(path, resolved, fields, &errs)
+	return resolved, errs.Err()
+}
+
+// resolveFields applies environment overrides and reads secret files
+// for the given fields, nested map fields are resolved recursively
+func resolveFields(path string, values map[string]interface{}, fields []Field, errs *ValidationErrors) {
+	secrets := []string{defaultSecret}
+	if fields != nil {
+		secrets = secrets[:0]
+		for _, f := range fields {
+			if f.Secret && f.Type != TypeMap {
+				secrets = append(secrets, f.Name)
+			}
+			if value, ok := os.LookupEnv(GetEnvKey(path + "." + f.Name)); ok {
+				values[f.Name] = value
+			}
+			if nested, ok := values[f.Name].(map[string]interface{}); ok {
+				switch {
+				case len(f.Fields) > 0:
+					resolveFields(path+"."+f.Name, nested, f.Fields, errs)
+				case f.Secret && f.Type == TypeMap:
+					resolveSecretMap(path+"."+f.Name, nested, errs)
+				}
+			}
+		}
+	} else {
+		for k := range values {
+			if value, ok := os.LookupEnv(GetEnvKey(path + "." + k)); ok {
+				values[k] = value
+			}
+		}
+	}
+
+	for _, name := range secrets {
+		key := name + fileSuffix
+		if value, ok := os.LookupEnv(GetEnvKey(path + "." + key)); ok {
+			values[key] = value
+		}
+		readSecretFile(path, values, name, errs)
+	}
+}
+
+// resolveSecretMap reads the _file keys of a secret map field
+// e.g. alice_file is read into alice
+func resolveSecretMap(path string, values map[string]interface{}, errs *ValidationErrors) {
+	for _, key := range sortedKeys(values) {
+		if strings.HasSuffix(key, fileSuffix) {
+			readSecretFile(path, values, strings.TrimSuffix(key, fileSuffix), errs)
+		}
+	}
+}
+
+// readSecretFile replaces the _file key of a secret with the content of the file
+func readSecretFile(path string, values map[string]interface{}, name string, errs *ValidationErrors) {
+	key := name + fileSuffix
+	file, ok := values[key].(string)
+	if !ok {
+		return
+	}
+	delete(values, key)
+	if file == "" {
+		return
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		*errs = append(*errs, NewValidationError(path+"."+key, "%v", err))
+		return
+	}
+	values[name] = strings.TrimRight(string(content), "\r\n")
+}
+
+// interpolate replaces ${ENV_VAR} in all strings of a value
+// returns a copy of maps and slices
+func interpolate(path string, value interface{}, errs *ValidationErrors) interface{} {
+	switch val := value.(type) {
+	case string:
+		return envPattern.ReplaceAllStringFunc(val, func(match string) string {
+			name := envPattern.FindStringSubmatch(match)[1]
+			env, ok := os.LookupEnv(name)
+			if !ok {
+				*errs = append(*errs, NewValidationError(path, "environment variable %s is not set", name))
+			}
+			return env
+		})
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(val))
+		for k, v := range val {
+			copied[k] = interpolate(path+"."+k, v, errs)
+		}
+		return copied
+	case map[interface{}]interface{}:
+		copied := make(map[string]interface{}, len(val))
+		for k, v := range val {
+			key, _ := k.(string)
+			copied[key] = interpolate(path+"."+key, v, errs)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(val))
+		for i, v := range val {
+			copied[i] = interpolate(path, v, errs)
+		}
+		return copied
+	}
+	return value
+}
+
+// RedactSettings returns a copy of the settings with the values of
+// secret keys replaced. Keys containing password, secret, token or basic_users are considered secrets
+func RedactSettings(settings map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(settings))
+	for k, v := range settings {
+		switch {
+		case isSecretKey(k):
+			copied[k] = redacted
+		default:
+			if nested, ok := v.(map[string]interface{}); ok {
+				v = RedactSettings(nested)
+			}
+			copied[k] = v
+		}
+	}
+	return copied
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	if strings.HasSuffix(key, fileSuffix) {
+		// file paths are not secrets
+		return false
+	}
+	for _, token := range secretTokens {
+		if strings.Contains(key, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package bergamot_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/alauda/bergamot"
+	"github.com/spf13/viper"
+)
+
+type secretDatasource struct {
+	user     string
+	password string
+	host     string
+}
+
+func (secretDatasource) Kind() string { return "secret" }
+
+func TestSecretsResolution(t *testing.T) {
+	bergamot.RegisterDatasource("secret", func(name string, config *viper.Viper) (bergamot.Datasource, error) {
+		return secretDatasource{
+			user:     config.GetString("user"),
+			password: config.GetString("password"),
+			host:     config.GetString("host"),
+		}, nil
+	},
+		bergamot.Field{Name: "host", Type: bergamot.TypeString, Required: true},
+		bergamot.Field{Name: "user", Type: bergamot.TypeString},
+		bergamot.Field{Name: "password", Type: bergamot.TypeString, Secret: true},
+	)
+
+	file, err := ioutil.TempFile("", "password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("s3cr3t\n")
+	file.Close()
+
+	os.Setenv("TEST_DB_USER", "admin")
+	os.Setenv("BERGAMOT_DATASOURCES_MAIN_HOST", "db.local")
+	defer os.Unsetenv("TEST_DB_USER")
+	defer os.Unsetenv("BERGAMOT_DATASOURCES_MAIN_HOST")
+
+	config := viper.New()
+	config.SetConfigType("yaml")
+	content := "datasources:\n  main:\n    type: secret\n    user: ${TEST_DB_USER}\n    password_file: " + file.Name() + "\n"
+	if err = config.ReadConfig(bytes.NewBufferString(content)); err != nil {
+		t.Fatalf("unexpected error reading config: %v", err)
+	}
+	if err = bergamot.Validate(config); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	ds, err := bergamot.DefaultParser{}.GetDatasources(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := secretDatasource{user: "admin", password: "s3cr3t", host: "db.local"}
+	if ds["main"] != expected {
+		t.Errorf("expected %#v got %#v", expected, ds["main"])
+	}
+	if config.GetString("datasources.main.password") != "" {
+		t.Errorf("resolved secret should not be set in the app configuration")
+	}
+
+	// missing files and environment variables are reported
+	config.Set("datasources", map[string]interface{}{
+		"main": map[string]interface{}{"type": "secret", "host": "${TEST_MISSING_ENV}", "password_file": "/missing/file"},
+	})
+	os.Unsetenv("BERGAMOT_DATASOURCES_MAIN_HOST")
+	errs, _ := bergamot.Validate(config).(bergamot.ValidationErrors)
+	if len(errs) != 2 || errs[0].Key != "datasources.main.host" || errs[1].Key != "datasources.main.password_file" {
+		t.Errorf("expected host and password_file errors got: %v", errs)
+	}
+}
+
+type usersDatasource map[string]string
+
+func (usersDatasource) Kind() string { return "users" }
+
+func TestSecretMapResolution(t *testing.T) {
+	bergamot.RegisterDatasource("users", func(name string, config *viper.Viper) (bergamot.Datasource, error) {
+		return usersDatasource(config.GetStringMapString("users")), nil
+	},
+		bergamot.Field{Name: "users", Type: bergamot.TypeMap, Secret: true},
+	)
+
+	file, err := ioutil.TempFile("", "password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("s3cr3t\n")
+	file.Close()
+
+	config := viper.New()
+	config.SetConfigType("yaml")
+	content := "datasources:\n  users:\n    users:\n      alice: plain\n      bob_file: " + file.Name() + "\n"
+	if err = config.ReadConfig(bytes.NewBufferString(content)); err != nil {
+		t.Fatalf("unexpected error reading config: %v", err)
+	}
+	ds, err := bergamot.DefaultParser{}.GetDatasources(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	users := ds["users"].(usersDatasource)
+	if len(users) != 2 || users["alice"] != "plain" || users["bob"] != "s3cr3t" {
+		t.Errorf("unexpected users: %v", users)
+	}
+}
+
+func TestRedactSettings(t *testing.T) {
+	settings := map[string]interface{}{
+		"component": "comp",
+		"datasources": map[string]interface{}{
+			"mysql": map[string]interface{}{"password": "secret", "password_file": "/run/secret", "user": "root"},
+		},
+		"api_token": "abc",
+		"servers": map[string]interface{}{
+			"http": map[string]interface{}{
+				"auth": map[string]interface{}{"basic_users": map[string]interface{}{"alice": "plain"}},
+			},
+		},
+	}
+	result := bergamot.RedactSettings(settings)
+	mysql := result["datasources"].(map[string]interface{})["mysql"].(map[string]interface{})
+	if mysql["password"] == "secret" || result["api_token"] == "abc" {
+		t.Errorf("secrets were not redacted: %v", result)
+	}
+	auth := result["servers"].(map[string]interface{})["http"].(map[string]interface{})["auth"].(map[string]interface{})
+	if auth["basic_users"] != "******" {
+		t.Errorf("basic users were not redacted: %v", auth)
+	}
+	if mysql["user"] != "root" || mysql["password_file"] != "/run/secret" || result["component"] != "comp" {
+		t.Errorf("non secret values were changed: %v", result)
+	}
+	if settings["api_token"] != "abc" {
+		t.Errorf("original settings should not be changed")
+	}
+}
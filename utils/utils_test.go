@@ -1,6 +1,7 @@
 package utils_test
 
 import (
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
@@ -68,3 +69,42 @@ func TestGetURL(t *testing.T) {
 		}
 	}
 }
+
+func TestClientIP(t *testing.T) {
+	type TestCase struct {
+		Name       string
+		RemoteAddr string
+		Headers    map[string]string
+		Expected   string
+	}
+
+	table := []TestCase{
+		{"connection", "192.0.2.1:1234", nil, "192.0.2.1"},
+		{"untrusted headers", "192.0.2.1:1234", map[string]string{"X-Real-Ip": "1.1.1.1", "X-Forwarded-For": "2.2.2.2"}, "192.0.2.1"},
+		{"real ip ignored", "10.0.0.1:1234", map[string]string{"X-Real-Ip": "1.1.1.1", "X-Forwarded-For": "2.2.2.2"}, "2.2.2.2"},
+		{"trusted hops skipped", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "2.2.2.2, 10.0.0.2"}, "2.2.2.2"},
+		{"spoofed forwarded", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "6.6.6.6, 203.0.113.9"}, "203.0.113.9"},
+		{"spoofed through trusted hops", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "6.6.6.6, 203.0.113.9, 10.0.0.2"}, "203.0.113.9"},
+		{"invalid hop", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "2.2.2.2, proxy"}, "10.0.0.1"},
+		{"only trusted hops", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"trusted without headers", "10.0.0.1:1234", nil, "10.0.0.1"},
+	}
+
+	trusted, err := utils.ParseTrustedProxies([]string{"10.0.0.0/8", "::1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, test := range table {
+		request := httptest.NewRequest("GET", "/", nil)
+		request.RemoteAddr = test.RemoteAddr
+		for k, v := range test.Headers {
+			request.Header.Set(k, v)
+		}
+		if ip := utils.ClientIP(request, trusted); ip != test.Expected {
+			t.Errorf("%d - %s -- expected %s got %s", i, test.Name, test.Expected, ip)
+		}
+	}
+	if _, err = utils.ParseTrustedProxies([]string{"proxy"}); err == nil {
+		t.Errorf("expected error for invalid trusted proxy")
+	}
+}
@@ -0,0 +1,243 @@
+// Package validate validates structs using the validate tag
+//
+// Rules are separated by commas, e.g.
+//
+//	Name  string   `json:"name" validate:"required,max=64,regexp=^[a-z0-9-]+$"`
+//	Kind  string   `json:"kind" validate:"enum=web|worker"`
+//	Ports []Port   `json:"ports" validate:"min=1"`
+//
+// min and max compare the value of numbers and the length of strings, slices and maps.
+// regexp must be the last rule as the expression can contain commas.
+// Nested structs and slices of structs are validated using the
+// parent.child and parent[index].child field names
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/alauda/bergamot/errors"
+)
+
+// Source source used in validation errors
+const Source = "validate"
+
+// Tag struct tag with the validation rules
+const Tag = "validate"
+
+var (
+	regexpLock sync.RWMutex
+	regexps    = map[string]*regexp.Regexp{}
+)
+
+// Struct validates v and returns an invalid_args error with all the failing fields
+// returns nil if v is valid
+func Struct(v interface{}) *errors.AlaudaError {
+	err := errors.New(Source, errors.ErrorCodeInvalidArgs)
+	validateValue(err, "", reflect.ValueOf(v))
+	if len(err.Fields) == 0 {
+		return nil
+	}
+	return err
+}
+
+// validateValue validates the fields of structs and the items of slices of structs
+func validateValue(err *errors.AlaudaError, prefix string, value reflect.Value) {
+	value = indirect(value)
+	switch value.Kind() {
+	case reflect.Struct:
+		validateStruct(err, prefix, value)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			validateValue(err, fmt.Sprintf("%s[%d]", prefix, i), value.Index(i))
+		}
+	}
+}
+
+func validateStruct(err *errors.AlaudaError, prefix string, value reflect.Value) {
+	kind := value.Type()
+	for i := 0; i < kind.NumField(); i++ {
+		field := kind.Field(i)
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+		name := fieldName(field)
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			name = ""
+		}
+		path := joinName(prefix, name)
+		fieldValue := value.Field(i)
+		for _, message := range validateField(fieldValue, field.Tag.Get(Tag)) {
+			err.AddFieldError(path, message)
+		}
+		validateValue(err, path, fieldValue)
+	}
+}
+
+// validateField applies the rules to the value and returns the error messages
+func validateField(value reflect.Value, rules string) (messages []string) {
+	if rules == "" {
+		return nil
+	}
+	empty := isEmpty(value)
+	value = indirect(value)
+	for rules != "" {
+		var rule string
+		if strings.HasPrefix(rules, "regexp=") {
+			rule, rules = rules, ""
+		} else if i := strings.Index(rules, ","); i >= 0 {
+			rule, rules = rules[:i], rules[i+1:]
+		} else {
+			rule, rules = rules, ""
+		}
+		name, param := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+		if name == "required" {
+			if empty {
+				return []string{"This field is required."}
+			}
+			continue
+		}
+		if empty {
+			// optional fields are only validated when set
+			continue
+		}
+		if message := applyRule(value, name, param); message != "" {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+func applyRule(value reflect.Value, name, param string) string {
+	switch name {
+	case "min", "max":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return fmt.Sprintf("Invalid %s rule %q.", name, param)
+		}
+		size, unit := measure(value)
+		if name == "min" && size < limit {
+			if unit != "" {
+				return fmt.Sprintf("Ensure this field has at least %s %s.", param, unit)
+			}
+			return fmt.Sprintf("Ensure this value is greater than or equal to %s.", param)
+		}
+		if name == "max" && size > limit {
+			if unit != "" {
+				return fmt.Sprintf("Ensure this field has no more than %s %s.", param, unit)
+			}
+			return fmt.Sprintf("Ensure this value is less than or equal to %s.", param)
+		}
+	case "enum":
+		options := strings.Split(param, "|")
+		actual := fmt.Sprint(value.Interface())
+		for _, option := range options {
+			if option == actual {
+				return ""
+			}
+		}
+		return fmt.Sprintf("Value must be one of: %s.", strings.Join(options, ", "))
+	case "regexp":
+		expression, err := getRegexp(param)
+		if err != nil {
+			return fmt.Sprintf("Invalid regexp rule %q.", param)
+		}
+		if value.Kind() != reflect.String || !expression.MatchString(value.String()) {
+			return fmt.Sprintf("Value does not match %s.", param)
+		}
+	default:
+		return fmt.Sprintf("Unknown validation rule %q.", name)
+	}
+	return ""
+}
+
+// measure returns the value of numbers or the length of strings, slices and maps
+// unit is empty for numbers
+func measure(value reflect.Value) (size float64, unit string) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), "characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), "items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), ""
+	}
+	return 0, ""
+}
+
+func getRegexp(expression string) (*regexp.Regexp, error) {
+	regexpLock.RLock()
+	compiled, ok := regexps[expression]
+	regexpLock.RUnlock()
+	if ok {
+		return compiled, nil
+	}
+	compiled, err := regexp.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	regexpLock.Lock()
+	regexps[expression] = compiled
+	regexpLock.Unlock()
+	return compiled, nil
+}
+
+// isEmpty returns true for nil pointers and zero values
+// slices and maps without elements are also empty
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	case reflect.Slice, reflect.Map, reflect.String, reflect.Array:
+		return value.Len() == 0
+	}
+	return reflect.DeepEqual(value.Interface(), reflect.Zero(value.Type()).Interface())
+}
+
+func indirect(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+// fieldName returns the name used in the json or form tag or the field name
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		if name := strings.Split(field.Tag.Get(tag), ",")[0]; name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func joinName(prefix, name string) string {
+	switch {
+	case prefix == "":
+		return name
+	case name == "":
+		return prefix
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,74 @@
+package validate_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alauda/bergamot/validate"
+)
+
+type port struct {
+	Name     string `json:"name" validate:"required,regexp=^[a-z]{1,3}$"`
+	Port     int    `json:"port" validate:"min=1,max=65535"`
+	Protocol string `json:"protocol" validate:"enum=tcp|udp"`
+}
+
+type service struct {
+	Name  string  `json:"name" validate:"required,max=8"`
+	Ports []port  `json:"ports" validate:"required"`
+	Owner *string `json:"owner,omitempty" validate:"min=2"`
+	Main  port    `json:"main"`
+}
+
+func TestStruct(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Value    service
+		Expected []string
+	}
+
+	short := "a"
+	table := []TestCase{
+		{
+			"valid",
+			service{Name: "web", Ports: []port{{Name: "tcp", Port: 80}}, Main: port{Name: "a"}},
+			nil,
+		},
+		{
+			"missing and too long",
+			service{Name: "too-long-name", Owner: &short, Main: port{Name: "a"}},
+			[]string{"name", "owner", "ports"},
+		},
+		{
+			"nested items",
+			service{Name: "web", Ports: []port{{Name: "ok"}, {Name: "NO", Port: 70000, Protocol: "http"}}},
+			[]string{"main.name", "ports[1].name", "ports[1].port", "ports[1].protocol"},
+		},
+	}
+
+	for i, test := range table {
+		err := validate.Struct(&test.Value)
+		if test.Expected == nil {
+			if err != nil {
+				t.Errorf("%d - %s -- unexpected error: %v", i, test.Name, err.Fields)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%d - %s -- expected error", i, test.Name)
+			continue
+		}
+		if err.Code != "invalid_args" || err.StatusCode != 400 {
+			t.Errorf("%d - %s -- unexpected error code: %v", i, test.Name, err)
+		}
+		var fields []string
+		for _, field := range test.Expected {
+			if _, ok := err.Fields[0][field]; ok {
+				fields = append(fields, field)
+			}
+		}
+		if !reflect.DeepEqual(fields, test.Expected) || len(err.Fields[0]) != len(test.Expected) {
+			t.Errorf("%d - %s -- expected fields %v got %v", i, test.Name, test.Expected, err.Fields[0])
+		}
+	}
+}